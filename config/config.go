@@ -9,51 +9,101 @@ type ProxyConfig struct {
 type RenameConfig struct {
 	Method string `yaml:"method"`
 	Flag   bool   `yaml:"flag"`
+	Tags   bool   `yaml:"tags"`
+	Unlock bool   `yaml:"unlock"`
+	Risk   bool   `yaml:"risk"`
+	// GeoipDb is the country database used by the geoip methods
+	GeoipDb string `yaml:"geoip-db"`
+	// Template replaces the built-in naming with a text/template
+	Template string `yaml:"template"`
+}
+type ProbeConfig struct {
+	Name      string            `yaml:"name"`
+	Url       string            `yaml:"url"`
+	Method    string            `yaml:"method"`
+	Headers   map[string]string `yaml:"headers"`
+	Status    []int             `yaml:"status"`
+	BodyRegex string            `yaml:"body-regex"`
+	Location  string            `yaml:"location"`
+	Tag       string            `yaml:"tag"`
+}
+type RiskConfig struct {
+	AsnDb      string   `yaml:"asn-db"`
+	Datacenter []string `yaml:"datacenter"`
+	Mobile     []string `yaml:"mobile"`
+}
+type SourceConfig struct {
+	MuteAfter  int `yaml:"mute-after"`
+	RetryAfter int `yaml:"retry-after"`
+	QuotaWarn  int `yaml:"quota-warn"`
+	ExpireWarn int `yaml:"expire-warn"`
+}
+type CategoryConfig struct {
+	Name   string `yaml:"name"`
+	Filter string `yaml:"filter"`
 }
 type SaveConfig struct {
-	BeforeSaveDo    []string `yaml:"before-save-do"`
-	AfterSaveDo     []string `yaml:"after-save-do"`
-	Method          []string `yaml:"method"`
-	Port            int      `yaml:"port"`
-	WebDAVURL       string   `yaml:"webdav-url"`
-	WebDAVUsername  string   `yaml:"webdav-username"`
-	WebDAVPassword  string   `yaml:"webdav-password"`
-	GithubToken     string   `yaml:"github-token"`
-	GithubGistID    string   `yaml:"github-gist-id"`
-	GithubAPIMirror string   `yaml:"github-api-mirror"`
-	WorkerURL       string   `yaml:"worker-url"`
-	WorkerToken     string   `yaml:"worker-token"`
+	BeforeSaveDo      []string         `yaml:"before-save-do"`
+	AfterSaveDo       []string         `yaml:"after-save-do"`
+	Method            []string         `yaml:"method"`
+	Formats           []string         `yaml:"formats"`
+	Categories        []CategoryConfig `yaml:"categories"`
+	Template          string           `yaml:"template"`
+	TemplateGroupType string           `yaml:"template-group-type"`
+	Port              int              `yaml:"port"`
+	WebDAVURL         string           `yaml:"webdav-url"`
+	WebDAVUsername    string           `yaml:"webdav-username"`
+	WebDAVPassword    string           `yaml:"webdav-password"`
+	GithubToken       string           `yaml:"github-token"`
+	GithubGistID      string           `yaml:"github-gist-id"`
+	GithubAPIMirror   string           `yaml:"github-api-mirror"`
+	WorkerURL         string           `yaml:"worker-url"`
+	WorkerToken       string           `yaml:"worker-token"`
+	ApiToken          string           `yaml:"api-token"`
 }
 type CheckConfig struct {
-	Concurrent           int      `yaml:"concurrent"`
-	Items                []string `yaml:"items"`
-	Interval             int      `yaml:"interval"`
-	Cron                 []string `yaml:"cron"`
-	RunAtStartup         bool     `yaml:"run-at-startup"`
-	Timeout              int      `yaml:"timeout"`
-	MinSpeed             int      `yaml:"min-speed"`
-	QualityLevel         int      `yaml:"quality-level"`
-	DownloadTimeout      int      `yaml:"download-timeout"`
-	DownloadSize         int      `yaml:"download-size"`
-	SpeedTestUrl         []string `yaml:"speed-test-url"`
-	SpeedSkipName        string   `yaml:"speed-skip-name"`
-	SpeedCheckConcurrent int      `yaml:"speed-check-concurrent"`
-	SpeedCount           int      `yaml:"speed-count"`
-	SpeedSave            bool     `yaml:"speed-save"`
+	Concurrent           int            `yaml:"concurrent"`
+	Items                []string       `yaml:"items"`
+	ItemTimeout          map[string]int `yaml:"item-timeout"`
+	Probes               []ProbeConfig  `yaml:"probes"`
+	EgressDedup          bool           `yaml:"egress-dedup"`
+	Interval             int            `yaml:"interval"`
+	Cron                 []string       `yaml:"cron"`
+	RunAtStartup         bool           `yaml:"run-at-startup"`
+	Timeout              int            `yaml:"timeout"`
+	MinSpeed             int            `yaml:"min-speed"`
+	QualityLevel         int            `yaml:"quality-level"`
+	DownloadTimeout      int            `yaml:"download-timeout"`
+	DownloadSize         int            `yaml:"download-size"`
+	SpeedTestUrl         []string       `yaml:"speed-test-url"`
+	SpeedSkipName        string         `yaml:"speed-skip-name"`
+	SpeedCheckConcurrent int            `yaml:"speed-check-concurrent"`
+	SpeedCount           int            `yaml:"speed-count"`
+	SpeedSave            bool           `yaml:"speed-save"`
+}
+type HistoryConfig struct {
+	Enable         bool    `yaml:"enable"`
+	Keep           int     `yaml:"keep"`
+	MinUptime      float32 `yaml:"min-uptime"`
+	PreferStable   bool    `yaml:"prefer-stable"`
+	IncrementalTTL int     `yaml:"incremental-ttl"`
 }
 type Config struct {
-	Check           CheckConfig  `yaml:"check"`
-	PrintProgress   bool         `yaml:"print-progress"`
-	Save            SaveConfig   `yaml:"save"`
-	SubUrlsReTry    int          `yaml:"sub-urls-retry"`
-	SubUrls         []string     `yaml:"sub-urls"`
-	TypeInclude     []string     `yaml:"type-include"`
-	MihomoApiUrl    string       `yaml:"mihomo-api-url"`
-	MihomoApiSecret string       `yaml:"mihomo-api-secret"`
-	Proxy           ProxyConfig  `yaml:"proxy"`
-	Rename          RenameConfig `yaml:"rename"`
-	LogLevel        string       `yaml:"log-level"`
-	WeworkBot       string       `yaml:"wework-bot"` // 新增企业微信机器人webhook地址
+	Check           CheckConfig   `yaml:"check"`
+	PrintProgress   bool          `yaml:"print-progress"`
+	Save            SaveConfig    `yaml:"save"`
+	SubUrlsReTry    int           `yaml:"sub-urls-retry"`
+	SubUrls         []SubConfig   `yaml:"sub-urls"`
+	TypeInclude     []string      `yaml:"type-include"`
+	MihomoApiUrl    string        `yaml:"mihomo-api-url"`
+	MihomoApiSecret string        `yaml:"mihomo-api-secret"`
+	Proxy           ProxyConfig   `yaml:"proxy"`
+	Rename          RenameConfig  `yaml:"rename"`
+	History         HistoryConfig `yaml:"history"`
+	Risk            RiskConfig    `yaml:"risk"`
+	Source          SourceConfig  `yaml:"source"`
+	LogLevel        string        `yaml:"log-level"`
+	WeworkBot       string        `yaml:"wework-bot"` // 新增企业微信机器人webhook地址
 }
 
 var GlobalConfig Config
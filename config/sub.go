@@ -0,0 +1,26 @@
+package config
+
+import "gopkg.in/yaml.v3"
+
+// SubConfig is an entry of sub-urls, written either as a plain url or as an
+// object with settings for that subscription.
+type SubConfig struct {
+	Url         string            `yaml:"url"`
+	Alias       string            `yaml:"alias,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	UserAgent   string            `yaml:"user-agent,omitempty"`
+	Retry       int               `yaml:"retry,omitempty"`
+	Include     string            `yaml:"include,omitempty"`
+	Exclude     string            `yaml:"exclude,omitempty"`
+	TypeInclude []string          `yaml:"type-include,omitempty"`
+	Direct      bool              `yaml:"direct,omitempty"`
+}
+
+func (s *SubConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = SubConfig{}
+		return node.Decode(&s.Url)
+	}
+	type plain SubConfig
+	return node.Decode((*plain)(s))
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/bestruirui/bestsub/config"
 	"github.com/bestruirui/bestsub/proxy"
 	"github.com/bestruirui/bestsub/proxy/checker"
+	"github.com/bestruirui/bestsub/proxy/history"
 	"github.com/bestruirui/bestsub/proxy/info"
 	"github.com/bestruirui/bestsub/proxy/saver"
 	"github.com/bestruirui/bestsub/utils"
@@ -73,9 +74,56 @@ func (app *App) Initialize() error {
 		return fmt.Errorf("init config watcher failed: %w", err)
 	}
 
+	if config.GlobalConfig.History.Enable {
+		historyPath := filepath.Join(utils.GetExecutablePath(), "history.db")
+		if err := history.Open(historyPath); err != nil {
+			return fmt.Errorf("init history failed: %w", err)
+		}
+		log.Info("history store: %v", historyPath)
+	}
+
+	if err := proxy.WatchLocalSources(); err != nil {
+		log.Error("watch local subscriptions failed: %v", err)
+	}
+
+	if err := proxy.SetSubCacheDir(filepath.Join(utils.GetExecutablePath(), "sub_cache")); err != nil {
+		log.Error("init subscription cache failed: %v", err)
+	}
+
+	if err := proxy.LoadSourceStats(filepath.Join(utils.GetExecutablePath(), "source_stats.json")); err != nil {
+		log.Error("load source stats failed: %v", err)
+	}
+
+	if method := config.GlobalConfig.Rename.Method; method == "geoip" || method == "regex-geoip" {
+		geoipPath := config.GlobalConfig.Rename.GeoipDb
+		if geoipPath == "" {
+			geoipPath = filepath.Join(utils.GetExecutablePath(), "geoip.metadb")
+		}
+		if err := info.GeoipInit(geoipPath); err != nil {
+			return fmt.Errorf("init geoip failed: %w", err)
+		}
+		log.Info("geoip database: %v", geoipPath)
+	}
+
+	if utils.Contains(config.GlobalConfig.Check.Items, "risk") {
+		asnPath := config.GlobalConfig.Risk.AsnDb
+		if asnPath == "" {
+			asnPath = filepath.Join(utils.GetExecutablePath(), "GeoLite2-ASN.mmdb")
+		}
+		if err := checker.OpenAsnDb(asnPath); err != nil {
+			return fmt.Errorf("init risk check failed: %w", err)
+		}
+		log.Info("asn database: %v", asnPath)
+	}
+
 	app.interval = config.GlobalConfig.Check.Interval
 	mihomoLog.SetLevel(mihomoLog.ERROR)
 	if utils.Contains(config.GlobalConfig.Save.Method, "http") {
+		saver.SetCheckTrigger(func() {
+			log.Info("check task triggered by api")
+			maintask(utils.GetTaskStatus().NextCheck)
+			utils.UpdateSubs()
+		})
 		saver.StartHTTPServer()
 	}
 	return nil
@@ -159,10 +207,21 @@ func (app *App) initConfigWatcher() error {
 				}
 			case <-reloadC:
 				log.Info("config file changed, reloading")
+				categories := config.GlobalConfig.Save.Categories
 				if err := app.loadConfig(); err != nil {
 					log.Error("reload config file failed: %v", err)
 				} else {
+					if err := saver.ValiCategories(); err != nil {
+						log.Error("reload save categories failed, keeping the previous ones: %v", err)
+						config.GlobalConfig.Save.Categories = categories
+					}
 					app.interval = config.GlobalConfig.Check.Interval
+					if err := checker.RegisterProbes(config.GlobalConfig.Check.Probes); err != nil {
+						log.Error("reload check probes failed: %v", err)
+					}
+					if err := proxy.WatchLocalSources(); err != nil {
+						log.Error("watch local subscriptions failed: %v", err)
+					}
 				}
 				reloadC = nil
 				reloadTimer = nil
@@ -192,6 +251,10 @@ func (app *App) Run() {
 		if app.c != nil {
 			app.c.Stop()
 		}
+		history.Close()
+		proxy.CloseLocalWatcher()
+		checker.CloseAsnDb()
+		info.GeoipClose()
 	}()
 
 	if config.GlobalConfig.Check.RunAtStartup {
@@ -259,6 +322,12 @@ func main() {
 	app.Run()
 }
 func maintask(nextCheck time.Time) {
+	if !utils.TaskStart(nextCheck) {
+		log.Warn("check task is already running, skip")
+		return
+	}
+	defer utils.TaskEnd()
+
 	startTime := time.Now()
 	proxies := make([]info.Proxy, 0)
 
@@ -267,6 +336,7 @@ func maintask(nextCheck time.Time) {
 	log.Info("get proxies success: %v proxies", len(proxies))
 
 	info.DeduplicateProxies(&proxies)
+	proxy.RecordSourceUnique(proxies)
 
 	log.Info("deduplicate proxies: %v proxies", len(proxies))
 
@@ -274,17 +344,38 @@ func maintask(nextCheck time.Time) {
 
 	pool, _ := ants.NewPool(config.GlobalConfig.Check.Concurrent)
 
+	utils.TaskStage(utils.StageCheck, len(proxies))
 	for i := range proxies {
 		wg.Add(1)
 		i := i
 		pool.Submit(func() {
 			defer wg.Done()
+			defer utils.TaskProgress()
 			proxyCheckTask(&proxies[i])
 		})
 	}
 
 	wg.Wait()
 
+	if history.Enabled() && config.GlobalConfig.History.IncrementalTTL > 0 {
+		cached := 0
+		for i := range proxies {
+			if proxies[i].Info.Cached {
+				cached++
+			}
+		}
+		log.Info("reused cached results: %v proxies", cached)
+	}
+
+	if history.Enabled() {
+		if err := history.Record(proxies, startTime); err != nil {
+			log.Error("record history failed: %v", err)
+		}
+		if err := history.Apply(proxies); err != nil {
+			log.Error("apply history failed: %v", err)
+		}
+	}
+
 	for i := 0; i < len(proxies); {
 		if proxies[i].Info.Alive {
 			i++
@@ -293,12 +384,38 @@ func maintask(nextCheck time.Time) {
 		}
 	}
 
+	// the source stats count every alive node, unstable ones included
+	proxy.RecordSourceAlive(proxies)
+
+	if minUptime := minUptime(); minUptime > 0 {
+		for i := 0; i < len(proxies); {
+			if proxies[i].Info.Uptime >= minUptime {
+				i++
+			} else {
+				proxies = append(proxies[:i], proxies[i+1:]...)
+			}
+		}
+		log.Info("stable proxies: %v proxies", len(proxies))
+	}
+
+	if config.GlobalConfig.Check.EgressDedup {
+		info.DeduplicateByEgress(&proxies)
+		log.Info("deduplicate by egress ip: %v proxies", len(proxies))
+	}
+
 	sort.Slice(proxies, func(i, j int) bool {
+		if history.Enabled() && config.GlobalConfig.History.PreferStable && proxies[i].Info.Uptime != proxies[j].Info.Uptime {
+			return proxies[i].Info.Uptime > proxies[j].Info.Uptime
+		}
 		return proxies[i].Info.Delay < proxies[j].Info.Delay
 	})
 
 	for i := range proxies {
 		proxies[i].Id = i
+		// 使用命名模板时在测速后统一命名
+		if config.GlobalConfig.Rename.Template != "" {
+			continue
+		}
 		name := fmt.Sprintf("%v %03d", proxies[i].Info.Country, proxies[i].Id)
 		if config.GlobalConfig.Rename.Flag {
 			proxies[i].CountryFlag()
@@ -308,6 +425,15 @@ func maintask(nextCheck time.Time) {
 		if proxies[i].Info.Rate != 0 {
 			name = fmt.Sprintf("%v x%.2f", name, proxies[i].Info.Rate)
 		}
+		if labels := proxies[i].Info.Unlock.Labels(); config.GlobalConfig.Rename.Unlock && len(labels) > 0 {
+			name = fmt.Sprintf("%v [%v]", name, strings.Join(labels, "|"))
+		}
+		if config.GlobalConfig.Rename.Risk && proxies[i].Info.IPType != "" {
+			name = fmt.Sprintf("%v [%v %d]", name, proxies[i].Info.IPType, proxies[i].Info.Risk)
+		}
+		if config.GlobalConfig.Rename.Tags && len(proxies[i].Info.Tags) > 0 {
+			name = fmt.Sprintf("%v [%v]", name, strings.Join(proxies[i].Info.Tags, "|"))
+		}
 		proxies[i].Raw["name"] = name
 	}
 
@@ -322,11 +448,13 @@ func maintask(nextCheck time.Time) {
 		speedCtx, speedCancel := context.WithCancel(context.Background())
 		var passedCount int32
 
+		utils.TaskStage(utils.StageSpeed, len(proxies))
 		for i := 0; i < len(proxies); i++ {
 			wg.Add(1)
 			idx := i
 			pool.Submit(func() {
 				defer wg.Done()
+				defer utils.TaskProgress()
 				if atomic.LoadInt32(&passedCount) >= int32(config.GlobalConfig.Check.SpeedCount) {
 					return
 				}
@@ -341,16 +469,9 @@ func maintask(nextCheck time.Time) {
 		for i := 0; i < len(proxies); i++ {
 			if proxies[i].Info.Speed > config.GlobalConfig.Check.MinSpeed && passed < config.GlobalConfig.Check.SpeedCount {
 				passed++
-				var speedStr string
-				switch {
-				case proxies[i].Info.Speed < 1024:
-					speedStr = fmt.Sprintf("%d KB/s", proxies[i].Info.Speed)
-				case proxies[i].Info.Speed < 1024*1024:
-					speedStr = fmt.Sprintf("%.2f MB/s", float64(proxies[i].Info.Speed)/1024)
-				default:
-					speedStr = fmt.Sprintf("%.2f GB/s", float64(proxies[i].Info.Speed)/(1024*1024))
+				if config.GlobalConfig.Rename.Template == "" {
+					proxies[i].Raw["name"] = fmt.Sprintf("%v | ⬇️ %s", proxies[i].Raw["name"], info.SpeedText(proxies[i].Info.Speed))
 				}
-				proxies[i].Raw["name"] = fmt.Sprintf("%v | ⬇️ %s", proxies[i].Raw["name"], speedStr)
 			} else {
 				if !config.GlobalConfig.Check.SpeedSave {
 					proxies[i].Info.SpeedSkip = true
@@ -358,13 +479,24 @@ func maintask(nextCheck time.Time) {
 			}
 		}
 		log.Info("end speed test, passed: %d/%d", passed, config.GlobalConfig.Check.SpeedCount)
+
+		if err := history.RecordSpeed(proxies, startTime); err != nil {
+			log.Error("record speed history failed: %v", err)
+		}
 	}
 
+	if config.GlobalConfig.Rename.Template != "" {
+		if err := info.RenderNames(config.GlobalConfig.Rename.Template, proxies); err != nil {
+			log.Error("rename proxies failed, keeping the previous names: %v", err)
+		}
+	}
+
+	utils.TaskStage(utils.StageSave, len(proxies))
 	// 获取实际保存的节点数量
 	savedProxies, savedCount := saver.SaveConfig(&proxies)
 	saveProxySource(&savedProxies)
 	duration := time.Since(startTime)
-	message := fmt.Sprintf("订阅检测处理完成!\n共处理节点数量: %v\n任务耗时: %.2f分钟\n下次任务时间: %s", savedCount, duration.Minutes(), nextCheck.Format("2006-01-02 15:04:05"))
+	message := fmt.Sprintf("订阅检测处理完成!\n共处理节点数量: %v\n任务耗时: %.2f分钟\n下次任务时间: %s\n%s", savedCount, duration.Minutes(), nextCheck.Format("2006-01-02 15:04:05"), proxy.SourceReport())
 	if err := utils.SendWeworkNotification(message); err != nil {
 		log.Error("发送企业微信通知失败: %v", err)
 	}
@@ -375,6 +507,14 @@ func maintask(nextCheck time.Time) {
 
 }
 
+// minUptime is the uptime a node needs to be kept, zero when history is off.
+func minUptime() float32 {
+	if !history.Enabled() {
+		return 0
+	}
+	return config.GlobalConfig.History.MinUptime
+}
+
 func saveProxySource(proxies *[]info.Proxy) {
 	proxySourceFileMutex.Lock()
 	defer proxySourceFileMutex.Unlock()
@@ -460,12 +600,12 @@ func proxyCheckTask(proxy *info.Proxy) {
 		return
 	}
 	defer proxy.Close()
-	checker := checker.NewChecker(proxy)
-	defer checker.Close()
+	c := checker.NewChecker(proxy)
+	defer c.Close()
 	aliveCount := 0
 	totalDelay := uint16(0)
 	for i := 0; i < 3; i++ {
-		checker.AliveTest("https://gstatic.com/generate_204", 204)
+		c.AliveTest("https://gstatic.com/generate_204", 204)
 		if proxy.Info.Alive {
 			aliveCount++
 			totalDelay += proxy.Info.Delay
@@ -478,16 +618,17 @@ func proxyCheckTask(proxy *info.Proxy) {
 
 	proxy.Info.Delay = totalDelay / uint16(aliveCount)
 
+	if history.Reuse(proxy, time.Duration(config.GlobalConfig.History.IncrementalTTL)*time.Minute, time.Now()) {
+		return
+	}
+
+	if config.GlobalConfig.Check.EgressDedup {
+		c.ExitIP()
+	}
+
 	for _, item := range config.GlobalConfig.Check.Items {
-		switch item {
-		case "openai":
-			checker.OpenaiTest()
-		case "youtube":
-			checker.YoutubeTest()
-		case "netflix":
-			checker.NetflixTest()
-		case "disney":
-			checker.DisneyTest()
+		if plugin, ok := checker.Lookup(item); ok {
+			c.Run(plugin)
 		}
 	}
 	switch config.GlobalConfig.Rename.Method {
@@ -500,19 +641,31 @@ func proxyCheckTask(proxy *info.Proxy) {
 		if proxy.Info.Country == "UN" {
 			proxy.CountryCodeFromApi()
 		}
+	case "geoip":
+		c.ExitIP()
+		proxy.CountryCodeFromGeoip()
+	case "regex-geoip":
+		proxy.CountryCodeRegex()
+		if proxy.Info.Country == "UN" {
+			c.ExitIP()
+			proxy.CountryCodeFromGeoip()
+		}
 	}
 
 }
 
 func proxySpeedCtxTask(p *info.Proxy, ctx context.Context, cancel context.CancelFunc, passedCount *int32) {
-	if p.New() != nil {
-		return
-	}
-	defer p.Close()
+	// 增量模式下复用了测速结果的节点不再测速
+	if !p.Info.Cached || p.Info.Speed == 0 {
+		if p.New() != nil {
+			return
+		}
+		defer p.Close()
 
-	checker := checker.NewChecker(p)
-	defer checker.Close()
-	checker.CheckSpeed()
+		checker := checker.NewChecker(p)
+		defer checker.Close()
+		checker.CheckSpeed()
+	}
 
 	// 测速后检查是否达标
 	if p.Info.Speed > config.GlobalConfig.Check.MinSpeed {
@@ -534,10 +687,28 @@ func checkConfig() {
 	}
 	log.Info("concurrents: %v", config.GlobalConfig.Check.Concurrent)
 	log.Info("save methods: %v", config.GlobalConfig.Save.Method)
+	if err := saver.ValiCategories(); err != nil {
+		log.Error("save categories: %v", err)
+		os.Exit(1)
+	}
+	for _, category := range config.GlobalConfig.Save.Categories {
+		log.Info(" - category %s: %s", category.Name, category.Filter)
+	}
+	if err := saver.ValiTemplate(); err != nil {
+		log.Error("save template: %v", err)
+		os.Exit(1)
+	}
+	if config.GlobalConfig.Save.Template != "" {
+		log.Info(" - mihomo template: %v", config.GlobalConfig.Save.Template)
+	}
 	if config.GlobalConfig.SubUrls == nil {
 		log.Error("sub-urls is required")
 		os.Exit(1)
 	}
+	if err := proxy.ValiSubs(); err != nil {
+		log.Error("sub-urls: %v", err)
+		os.Exit(1)
+	}
 	switch config.GlobalConfig.Rename.Method {
 	case "api":
 		log.Info("rename method: api")
@@ -545,10 +716,19 @@ func checkConfig() {
 		log.Info("rename method: regex")
 	case "mix":
 		log.Info("rename method: mix")
+	case "geoip", "regex-geoip":
+		log.Info("rename method: %v", config.GlobalConfig.Rename.Method)
 	default:
-		log.Error("rename-method must be one of api, regex, mix")
+		log.Error("rename-method must be one of api, regex, mix, geoip, regex-geoip")
 		os.Exit(1)
 	}
+	if config.GlobalConfig.Rename.Template != "" {
+		if _, err := info.ParseNameTemplate(config.GlobalConfig.Rename.Template); err != nil {
+			log.Error("rename template: %v", err)
+			os.Exit(1)
+		}
+		log.Info("rename template: %v", config.GlobalConfig.Rename.Template)
+	}
 	if config.GlobalConfig.Proxy.Type == "http" {
 		log.Info("proxy type: http")
 	} else if config.GlobalConfig.Proxy.Type == "socks" {
@@ -561,10 +741,26 @@ func checkConfig() {
 		log.Error("check-interval must be greater than 10 minutes")
 		os.Exit(1)
 	}
+	if err := checker.RegisterProbes(config.GlobalConfig.Check.Probes); err != nil {
+		log.Error("check probes: %v", err)
+		os.Exit(1)
+	}
+	if config.GlobalConfig.Check.EgressDedup {
+		log.Info("deduplicate by egress ip: enabled")
+	}
 	if len(config.GlobalConfig.Check.Items) == 0 {
 		log.Info("check items: none")
 	} else {
 		log.Info("check items: %v", config.GlobalConfig.Check.Items)
+		for _, item := range config.GlobalConfig.Check.Items {
+			if _, ok := checker.Lookup(item); !ok && item != "speed" {
+				log.Error("unknown check item: %s, available: speed, %s", item, strings.Join(checker.Names(), ", "))
+				os.Exit(1)
+			}
+		}
+		for item, timeout := range config.GlobalConfig.Check.ItemTimeout {
+			log.Info(" - %s timeout: %v ms", item, timeout)
+		}
 		if utils.Contains(config.GlobalConfig.Check.Items, "speed") {
 			if config.GlobalConfig.Check.SpeedCheckConcurrent <= 0 {
 				config.GlobalConfig.Check.SpeedCheckConcurrent = 3
@@ -583,9 +779,20 @@ func checkConfig() {
 			}
 		}
 	}
+	if config.GlobalConfig.Source.MuteAfter > 0 {
+		log.Info("mute subscriptions after %v failed runs", config.GlobalConfig.Source.MuteAfter)
+	}
 	if len(config.GlobalConfig.TypeInclude) > 0 {
 		log.Info("type include: %v", config.GlobalConfig.TypeInclude)
 	}
+	if config.GlobalConfig.History.Enable {
+		log.Info("history: keep %v records, min uptime %v%%, prefer stable: %v", config.GlobalConfig.History.Keep, config.GlobalConfig.History.MinUptime, config.GlobalConfig.History.PreferStable)
+		if config.GlobalConfig.History.IncrementalTTL > 0 {
+			log.Info(" - incremental check ttl: %v minutes", config.GlobalConfig.History.IncrementalTTL)
+		}
+	} else if config.GlobalConfig.History.IncrementalTTL > 0 {
+		log.Warn("history.incremental-ttl needs history.enable, incremental check is disabled")
+	}
 
 	if config.GlobalConfig.MihomoApiUrl != "" {
 		version, err := utils.GetVersion()
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// subCacheDir holds the last good body of every subscription, empty disables
+// the cache.
+var subCacheDir string
+
+// subCacheEntry describes a cached subscription body.
+type subCacheEntry struct {
+	// Url is the fetched url, it differs from the cache key when the
+	// configured url has date placeholders
+	Url          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last-modified,omitempty"`
+	Time         time.Time `json:"time"`
+	body         []byte
+}
+
+// SetSubCacheDir sets the directory of the subscription cache.
+func SetSubCacheDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create subscription cache dir failed: %w", err)
+	}
+	subCacheDir = dir
+	return nil
+}
+
+func subCachePath(subUrl string) string {
+	sum := sha1.Sum([]byte(subUrl))
+	return filepath.Join(subCacheDir, hex.EncodeToString(sum[:]))
+}
+
+// loadSubCache returns the cached body of a subscription, nil if there is none.
+func loadSubCache(subUrl string) *subCacheEntry {
+	if subCacheDir == "" {
+		return nil
+	}
+	path := subCachePath(subUrl)
+	meta, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	var entry subCacheEntry
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return nil
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	entry.body = body
+	return &entry
+}
+
+func saveSubCache(subUrl string, entry *subCacheEntry) error {
+	if subCacheDir == "" {
+		return nil
+	}
+	path := subCachePath(subUrl)
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, entry.body); err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".json", meta)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
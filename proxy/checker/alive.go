@@ -7,7 +7,7 @@ import (
 )
 
 func (c *Checker) AliveTest(url string, expectedStatus int) {
-	ctx, cancel := context.WithCancel(c.Proxy.Ctx)
+	ctx, cancel := context.WithCancel(c.Ctx)
 	defer cancel()
 
 	start := time.Now()
@@ -16,7 +16,7 @@ func (c *Checker) AliveTest(url string, expectedStatus int) {
 	if err != nil {
 		return
 	}
-	resp, err := c.Proxy.Client.Do(req)
+	resp, err := c.Client.Do(req)
 
 	if err != nil {
 		return
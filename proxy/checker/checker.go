@@ -1,16 +1,25 @@
 package checker
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/bestruirui/bestsub/proxy/info"
 )
 
 type Checker struct {
 	Proxy *info.Proxy
+	// Ctx and Client are used by the checks, Run narrows them to the
+	// timeout of the running plugin.
+	Ctx    context.Context
+	Client *http.Client
 }
 
 func NewChecker(proxy *info.Proxy) *Checker {
 	return &Checker{
-		Proxy: proxy,
+		Proxy:  proxy,
+		Ctx:    proxy.Ctx,
+		Client: proxy.Client,
 	}
 }
 
@@ -1,12 +1,25 @@
 package checker
 
 import (
+	"context"
+	"io"
+	"net"
 	"net/http"
+	"strings"
 )
 
+func init() {
+	Register(NewPlugin("cloudflare", func(c *Checker) bool {
+		c.CloudflareTest()
+		return c.Proxy.Info.Unlock.Cloudflare
+	}))
+}
+
 func (c *Checker) CloudflareTest() {
+	ctx, cancel := context.WithCancel(c.Ctx)
+	defer cancel()
 
-	req, err := http.NewRequest("GET", "https://www.cloudflare.com", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.cloudflare.com", nil)
 	if err != nil {
 		return
 	}
@@ -15,13 +28,58 @@ func (c *Checker) CloudflareTest() {
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
 	req.Header.Set("Connection", "close")
 
-	resp, err := c.Proxy.Client.Do(req)
+	resp, err := c.Client.Do(req)
 	if err != nil {
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 200 {
-		c.Proxy.Info.Alive = true
+		c.Proxy.Info.Unlock.Cloudflare = true
+	}
+}
+
+// trace reads the key=value fields of a cloudflare cdn-cgi/trace page.
+func (c *Checker) trace(ctx context.Context, url string) map[string]string {
+	fields := make(map[string]string)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fields
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fields
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fields
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// traceRegion reads the loc field of a cloudflare cdn-cgi/trace page.
+func (c *Checker) traceRegion(ctx context.Context, url string) string {
+	return c.trace(ctx, url)["loc"]
+}
+
+// ExitIP returns the address the node connects out from, it is looked up
+// once and kept in ProxyInfo.IP.
+func (c *Checker) ExitIP() string {
+	if c.Proxy.Info.IP != "" {
+		return c.Proxy.Info.IP
+	}
+	ctx, cancel := context.WithCancel(c.Ctx)
+	defer cancel()
+
+	if ip := net.ParseIP(c.trace(ctx, "https://www.cloudflare.com/cdn-cgi/trace")["ip"]); ip != nil {
+		c.Proxy.Info.IP = ip.String()
 	}
+	return c.Proxy.Info.IP
 }
@@ -7,10 +7,19 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/bestruirui/bestsub/proxy/info"
 )
 
+func init() {
+	Register(NewPlugin("disney", func(c *Checker) bool {
+		c.DisneyTest()
+		return c.Proxy.Info.Unlock.Disney.Unlocked()
+	}))
+}
+
 func (c *Checker) DisneyTest() {
-	ctx, cancel := context.WithCancel(c.Proxy.Ctx)
+	ctx, cancel := context.WithCancel(c.Ctx)
 	defer cancel()
 
 	const (
@@ -29,7 +38,7 @@ func (c *Checker) DisneyTest() {
 	req.Header.Set("Authorization", authBear)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.Proxy.Client.Do(req)
+	resp, err := c.Client.Do(req)
 	if err != nil {
 		return
 	}
@@ -60,7 +69,7 @@ func (c *Checker) DisneyTest() {
 	req.Header.Set("Authorization", authBear)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err = c.Proxy.Client.Do(req)
+	resp, err = c.Client.Do(req)
 	if err != nil {
 		return
 	}
@@ -77,6 +86,7 @@ func (c *Checker) DisneyTest() {
 	}
 
 	if errDesc, ok := tokenResp["error_description"].(string); ok && errDesc == "forbidden-location" {
+		c.Proxy.Info.Unlock.Disney.Set(info.UnlockBlocked, "")
 		return
 	}
 
@@ -95,7 +105,7 @@ func (c *Checker) DisneyTest() {
 	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Authorization", authBear)
 
-	resp, err = c.Proxy.Client.Do(req)
+	resp, err = c.Client.Do(req)
 	if err != nil {
 		return
 	}
@@ -127,6 +137,14 @@ func (c *Checker) DisneyTest() {
 	}
 
 	inSupportedLocation, _ := session["inSupportedLocation"].(bool)
+	region := ""
+	if location, ok := session["location"].(map[string]interface{}); ok {
+		region, _ = location["countryCode"].(string)
+	}
 
-	c.Proxy.Info.Unlock.Disney = inSupportedLocation
+	if inSupportedLocation {
+		c.Proxy.Info.Unlock.Disney.Set(info.UnlockFull, region)
+	} else {
+		c.Proxy.Info.Unlock.Disney.Set(info.UnlockBlocked, region)
+	}
 }
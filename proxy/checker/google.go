@@ -1,8 +1,28 @@
 package checker
 
+import (
+	"context"
+	"net/http"
+)
+
+func init() {
+	Register(NewPlugin("google", func(c *Checker) bool {
+		c.GoogleTest()
+		return c.Proxy.Info.Unlock.Google
+	}))
+}
+
 func (c *Checker) GoogleTest() {
-	resp, err := c.Proxy.Client.Get("http://www.google.com/generate_204")
+	ctx, cancel := context.WithCancel(c.Ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://www.google.com/generate_204", nil)
+	if err != nil {
+		return
+	}
+	resp, err := c.Client.Do(req)
 	if err != nil {
+		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == 204 {
@@ -2,25 +2,99 @@ package checker
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bestruirui/bestsub/proxy/info"
 )
 
+func init() {
+	Register(NewPlugin("netflix", func(c *Checker) bool {
+		c.NetflixTest()
+		return c.Proxy.Info.Unlock.Netflix.Unlocked()
+	}))
+}
+
+const (
+	// netflixOriginal is a netflix original, available in every netflix region
+	netflixOriginal = "80018499"
+	// netflixLicensed is a licensed title only shown with a full catalog
+	netflixLicensed = "70143836"
+)
+
+// netflixBaseURL can be pointed at a stand-in server.
+var netflixBaseURL = "https://www.netflix.com"
+
+var netflixRegionRegex = regexp.MustCompile(`(?s)"requestCountry":\{.{0,512}?"id":"([A-Z]{2})"`)
+
 func (c *Checker) NetflixTest() {
-	ctx, cancel := context.WithCancel(c.Proxy.Ctx)
+	ctx, cancel := context.WithCancel(c.Ctx)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.netflix.com/title/81280792", nil)
+	originalStatus, originalRegion, err := c.netflixTitle(ctx, netflixOriginal)
 	if err != nil {
 		return
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-	resp, err := c.Proxy.Client.Do(req)
+	licensedStatus, licensedRegion, err := c.netflixTitle(ctx, netflixLicensed)
 	if err != nil {
 		return
 	}
+
+	region := licensedRegion
+	if region == "" {
+		region = originalRegion
+	}
+	switch {
+	case licensedStatus == 200:
+		c.Proxy.Info.Unlock.Netflix.Set(info.UnlockFull, region)
+	case originalStatus == 200:
+		c.Proxy.Info.Unlock.Netflix.Set(info.UnlockOriginals, region)
+	default:
+		c.Proxy.Info.Unlock.Netflix.Set(info.UnlockBlocked, region)
+	}
+}
+
+// netflixTitle requests a title page and returns the status code and, when
+// the title is available, the region read from the page or from the path
+// netflix redirected to.
+func (c *Checker) netflixTitle(ctx context.Context, id string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", netflixBaseURL+"/title/"+id, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 200 {
-		c.Proxy.Info.Unlock.Netflix = true
+	if resp.StatusCode != 200 {
+		return resp.StatusCode, "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return 0, "", err
+	}
+	if match := netflixRegionRegex.FindSubmatch(body); match != nil {
+		return resp.StatusCode, string(match[1]), nil
+	}
+	return resp.StatusCode, netflixRegion(resp.Request.URL), nil
+}
+
+// netflixRegion reads the region from the path netflix redirects to, such as
+// /jp/title/80018499 or /jp-en/title/80018499, no prefix means US.
+func netflixRegion(u *url.URL) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if segment == "title" {
+		return "US"
+	}
+	region, _, _ := strings.Cut(segment, "-")
+	if len(region) != 2 {
+		return ""
 	}
+	return region
 }
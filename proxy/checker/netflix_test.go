@@ -0,0 +1,98 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/bestsub/proxy/info"
+)
+
+// netflixServer points the checks at a stand-in server for the test.
+func netflixServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	saved := netflixBaseURL
+	t.Cleanup(func() { netflixBaseURL = saved })
+	netflixBaseURL = server.URL
+}
+
+// titleHandler serves the titles in available with body and every other
+// title with 404.
+func titleHandler(body string, available ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		for _, title := range available {
+			if id == title {
+				w.Write([]byte(body))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func runNetflix(t *testing.T) info.UnlockResult {
+	t.Helper()
+	proxy := &info.Proxy{}
+	c := &Checker{Proxy: proxy, Ctx: context.Background(), Client: &http.Client{}}
+	c.NetflixTest()
+	return proxy.Info.Unlock.Netflix
+}
+
+func TestNetflix(t *testing.T) {
+	page := `<script>{"requestCountry":{"supportedLocales":[],"id":"JP"}}</script>`
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantLevel string
+		region    string
+	}{
+		{"full", titleHandler(page, netflixOriginal, netflixLicensed), info.UnlockFull, "JP"},
+		{"licensed only", titleHandler(page, netflixLicensed), info.UnlockFull, "JP"},
+		{"originals only", titleHandler(page, netflixOriginal), info.UnlockOriginals, "JP"},
+		{"blocked", titleHandler(page), info.UnlockBlocked, ""},
+		{"no region in page", titleHandler("<html></html>", netflixOriginal, netflixLicensed), info.UnlockFull, "US"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			netflixServer(t, tt.handler)
+			result := runNetflix(t)
+			if result.Level != tt.wantLevel || result.Region != tt.region {
+				t.Errorf("got %s/%s, want %s/%s", result.Level, result.Region, tt.wantLevel, tt.region)
+			}
+		})
+	}
+}
+
+func TestNetflixRegionFromRedirect(t *testing.T) {
+	netflixServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/title/") {
+			http.Redirect(w, r, "/sg-zh"+r.URL.Path, http.StatusFound)
+			return
+		}
+		titleHandler("<html></html>", netflixOriginal)(w, r)
+	})
+	result := runNetflix(t)
+	if result.Level != info.UnlockOriginals || result.Region != "SG" {
+		t.Errorf("got %s/%s, want %s/SG", result.Level, result.Region, info.UnlockOriginals)
+	}
+}
+
+func TestNetflixRegion(t *testing.T) {
+	tests := map[string]string{
+		"/title/80018499":       "US",
+		"/jp/title/80018499":    "jp",
+		"/jp-en/title/80018499": "jp",
+		"/browse":               "",
+	}
+	for path, want := range tests {
+		if got := netflixRegion(&url.URL{Path: path}); got != want {
+			t.Errorf("netflixRegion(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -5,10 +5,19 @@ import (
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/bestruirui/bestsub/proxy/info"
 )
 
+func init() {
+	Register(NewPlugin("openai", func(c *Checker) bool {
+		c.OpenaiTest()
+		return c.Proxy.Info.Unlock.Chatgpt.Unlocked()
+	}))
+}
+
 func (c *Checker) OpenaiTest() {
-	ctx, cancel := context.WithCancel(c.Proxy.Ctx)
+	ctx, cancel := context.WithCancel(c.Ctx)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", "https://android.chat.openai.com", nil)
@@ -17,19 +26,19 @@ func (c *Checker) OpenaiTest() {
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 
-	resp, err := c.Proxy.Client.Do(req)
+	resp, err := c.Client.Do(req)
 	if err != nil {
 		return
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == 403 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return
-		}
-		if strings.Contains(string(body), "Request is not allowed. Please try again later.") {
-			c.Proxy.Info.Unlock.Chatgpt = true
-		}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	level := info.UnlockBlocked
+	if resp.StatusCode == 403 && strings.Contains(string(body), "Request is not allowed. Please try again later.") {
+		level = info.UnlockFull
 	}
+	c.Proxy.Info.Unlock.Chatgpt.Set(level, c.traceRegion(ctx, "https://chat.openai.com/cdn-cgi/trace"))
 }
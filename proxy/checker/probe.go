@@ -0,0 +1,156 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/dlclark/regexp2"
+)
+
+const probeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
+// Probe is a check item declared in check.probes, it requests a URL through
+// the node and matches the status code, body and redirect location.
+type Probe struct {
+	cfg      config.ProbeConfig
+	body     *regexp2.Regexp
+	location *regexp2.Regexp
+}
+
+func NewProbe(cfg config.ProbeConfig) (*Probe, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("probe name is empty")
+	}
+	if u, err := url.Parse(cfg.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("probe %s: invalid url %q", cfg.Name, cfg.Url)
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	if cfg.Tag == "" {
+		cfg.Tag = cfg.Name
+	}
+
+	probe := &Probe{cfg: cfg}
+	var err error
+	if cfg.BodyRegex != "" {
+		if probe.body, err = regexp2.Compile(cfg.BodyRegex, regexp2.None); err != nil {
+			return nil, fmt.Errorf("probe %s: invalid body-regex: %w", cfg.Name, err)
+		}
+	}
+	if cfg.Location != "" {
+		if probe.location, err = regexp2.Compile(cfg.Location, regexp2.None); err != nil {
+			return nil, fmt.Errorf("probe %s: invalid location: %w", cfg.Name, err)
+		}
+	}
+	return probe, nil
+}
+
+func (p *Probe) Name() string { return p.cfg.Name }
+
+func (p *Probe) Check(c *Checker) bool {
+	ctx, cancel := context.WithCancel(c.Ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, p.cfg.Method, p.cfg.Url, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("User-Agent", probeUserAgent)
+	for key, value := range p.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 需要匹配跳转地址时不跟随跳转
+	client := c.Client
+	if p.location != nil {
+		client = &http.Client{
+			Timeout:   c.Client.Timeout,
+			Transport: c.Client.Transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if !p.statusMatch(resp.StatusCode) {
+		return false
+	}
+	if p.location != nil {
+		if ok, _ := p.location.MatchString(resp.Header.Get("Location")); !ok {
+			return false
+		}
+	}
+	if p.body != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return false
+		}
+		if ok, _ := p.body.MatchString(string(body)); !ok {
+			return false
+		}
+	}
+
+	if !slices.Contains(c.Proxy.Info.Tags, p.cfg.Tag) {
+		c.Proxy.Info.Tags = append(c.Proxy.Info.Tags, p.cfg.Tag)
+	}
+	return true
+}
+
+// statusMatch accepts the configured status codes, or any 2xx when none is
+// set, any 3xx as well when a redirect location is expected.
+func (p *Probe) statusMatch(status int) bool {
+	if len(p.cfg.Status) > 0 {
+		return slices.Contains(p.cfg.Status, status)
+	}
+	if p.location != nil && status >= 300 && status < 400 {
+		return true
+	}
+	return status >= 200 && status < 300
+}
+
+// RegisterProbes replaces the registered probes with the ones from config.
+func RegisterProbes(probes []config.ProbeConfig) error {
+	created := make([]*Probe, 0, len(probes))
+	for _, cfg := range probes {
+		probe, err := NewProbe(cfg)
+		if err != nil {
+			return err
+		}
+		if existing, ok := Lookup(probe.Name()); ok {
+			if _, isProbe := existing.(*Probe); !isProbe {
+				return fmt.Errorf("probe %s: name conflicts with a built-in check item", probe.Name())
+			}
+		}
+		for _, other := range created {
+			if other.Name() == probe.Name() {
+				return fmt.Errorf("probe %s: duplicate name", probe.Name())
+			}
+		}
+		created = append(created, probe)
+	}
+
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+	for name, plugin := range plugins {
+		if _, ok := plugin.(*Probe); ok {
+			delete(plugins, name)
+		}
+	}
+	for _, probe := range created {
+		plugins[probe.Name()] = probe
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/bestsub/config"
+)
+
+// Plugin is a check item that can be selected in check.items. Check reports
+// whether the proxy passed, the result is stored in ProxyInfo.Checks under
+// the plugin name.
+type Plugin interface {
+	Name() string
+	Check(c *Checker) bool
+}
+
+type funcPlugin struct {
+	name  string
+	check func(c *Checker) bool
+}
+
+func (p funcPlugin) Name() string          { return p.name }
+func (p funcPlugin) Check(c *Checker) bool { return p.check(c) }
+
+// NewPlugin wraps a check function as a Plugin.
+func NewPlugin(name string, check func(c *Checker) bool) Plugin {
+	return funcPlugin{name: name, check: check}
+}
+
+var (
+	plugins     = make(map[string]Plugin)
+	pluginsLock sync.RWMutex
+)
+
+// Register adds a plugin, a plugin with the same name is replaced.
+func Register(plugin Plugin) {
+	pluginsLock.Lock()
+	defer pluginsLock.Unlock()
+	plugins[plugin.Name()] = plugin
+}
+
+func Lookup(name string) (Plugin, bool) {
+	pluginsLock.RLock()
+	defer pluginsLock.RUnlock()
+	plugin, ok := plugins[name]
+	return plugin, ok
+}
+
+func Names() []string {
+	pluginsLock.RLock()
+	defer pluginsLock.RUnlock()
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run runs a plugin with the timeout configured in check.item-timeout and
+// records its result on the proxy.
+func (c *Checker) Run(plugin Plugin) bool {
+	ctx, cancel := context.WithCancel(c.Proxy.Ctx)
+	client := c.Proxy.Client
+	if timeout, ok := config.GlobalConfig.Check.ItemTimeout[plugin.Name()]; ok && timeout > 0 {
+		cancel()
+		ctx, cancel = context.WithTimeout(c.Proxy.Ctx, time.Duration(timeout)*time.Millisecond)
+		client = &http.Client{
+			Timeout:   time.Duration(timeout) * time.Millisecond,
+			Transport: c.Proxy.Client.Transport,
+		}
+	}
+	defer cancel()
+
+	c.Ctx, c.Client = ctx, client
+	defer func() {
+		c.Ctx, c.Client = c.Proxy.Ctx, c.Proxy.Client
+	}()
+
+	passed := plugin.Check(c)
+	if c.Proxy.Info.Checks == nil {
+		c.Proxy.Info.Checks = make(map[string]bool)
+	}
+	c.Proxy.Info.Checks[plugin.Name()] = passed
+	return passed
+}
@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func init() {
+	Register(NewPlugin("risk", func(c *Checker) bool {
+		c.RiskTest()
+		return c.Proxy.Info.IPType == IPTypeResidential || c.Proxy.Info.IPType == IPTypeMobile
+	}))
+}
+
+// IP types reported by the risk check
+const (
+	IPTypeDatacenter  = "datacenter"
+	IPTypeResidential = "residential"
+	IPTypeMobile      = "mobile"
+)
+
+// riskScores is the risk of each IP type, an address missing from the ASN
+// database scores riskUnknown.
+var riskScores = map[string]int{
+	IPTypeMobile:      10,
+	IPTypeResidential: 20,
+	IPTypeDatacenter:  80,
+}
+
+const riskUnknown = 50
+
+// Keywords matched against the AS organization, extended by risk.datacenter
+// and risk.mobile in config.
+var (
+	datacenterKeywords = []string{
+		"hosting", "cloud", "server", "servers", "data center", "datacenter", "colocation", "vps", "cdn",
+		"amazon", "aws", "google", "microsoft", "azure", "oracle", "alibaba", "aliyun", "tencent",
+		"huawei", "digitalocean", "linode", "akamai", "vultr", "choopa", "ovh", "hetzner", "contabo",
+		"leaseweb", "m247", "cloudflare", "fastly", "kamatera", "bandwagon", "it7", "dmit", "hostinger",
+		"ucloud", "psychz", "quadranet", "cogent", "zenlayer", "gcore", "datacamp", "frantech",
+	}
+	mobileKeywords = []string{
+		"mobile", "wireless", "cellular", "docomo", "softbank", "kddi", "t-mobile",
+		"vodafone", "verizon wireless", "at&t mobility", "china mobile", "chunghwa", "smartone",
+		"hutchison", "csl", "telstra mobile", "jio",
+	}
+)
+
+type asnRecord struct {
+	Number uint   `maxminddb:"autonomous_system_number"`
+	Org    string `maxminddb:"autonomous_system_organization"`
+}
+
+var (
+	asnDb     *maxminddb.Reader
+	asnDbLock sync.RWMutex
+)
+
+// OpenAsnDb opens a GeoLite2-ASN compatible mmdb file used by the risk check.
+func OpenAsnDb(path string) error {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return fmt.Errorf("open asn db failed: %w", err)
+	}
+	asnDbLock.Lock()
+	defer asnDbLock.Unlock()
+	if asnDb != nil {
+		asnDb.Close()
+	}
+	asnDb = db
+	return nil
+}
+
+func CloseAsnDb() {
+	asnDbLock.Lock()
+	defer asnDbLock.Unlock()
+	if asnDb != nil {
+		asnDb.Close()
+		asnDb = nil
+	}
+}
+
+// IPRisk is the classification of an address.
+type IPRisk struct {
+	Asn   uint
+	AsOrg string
+	Type  string
+	Score int
+}
+
+// ClassifyIP looks up the AS of an address in the ASN database and derives
+// its type and risk score, it does not touch the network.
+func ClassifyIP(ip net.IP) (IPRisk, error) {
+	asnDbLock.RLock()
+	defer asnDbLock.RUnlock()
+	if asnDb == nil {
+		return IPRisk{}, fmt.Errorf("asn db is not opened")
+	}
+
+	var record asnRecord
+	if err := asnDb.Lookup(ip, &record); err != nil {
+		return IPRisk{}, fmt.Errorf("lookup asn failed: %w", err)
+	}
+	if record.Number == 0 {
+		return IPRisk{Score: riskUnknown}, nil
+	}
+
+	risk := IPRisk{Asn: record.Number, AsOrg: record.Org, Type: IPTypeResidential}
+	org := strings.ToLower(record.Org)
+	switch {
+	case matchKeyword(org, mobileKeywords, config.GlobalConfig.Risk.Mobile):
+		risk.Type = IPTypeMobile
+	case matchKeyword(org, datacenterKeywords, config.GlobalConfig.Risk.Datacenter):
+		risk.Type = IPTypeDatacenter
+	}
+	risk.Score = riskScores[risk.Type]
+	return risk, nil
+}
+
+// matchKeyword reports whether org contains one of the keywords as whole
+// words, so that short keywords such as "csl" do not match inside other names.
+func matchKeyword(org string, lists ...[]string) bool {
+	for _, list := range lists {
+		for _, keyword := range list {
+			if keyword != "" && containsWord(org, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsWord(text string, word string) bool {
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], word)
+		if idx == -1 {
+			return false
+		}
+		start := offset + idx
+		end := start + len(word)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
+
+func (c *Checker) RiskTest() {
+	ip := net.ParseIP(c.ExitIP())
+	if ip == nil {
+		return
+	}
+	risk, err := ClassifyIP(ip)
+	if err != nil {
+		return
+	}
+	c.Proxy.Info.Asn = risk.Asn
+	c.Proxy.Info.AsOrg = risk.AsOrg
+	c.Proxy.Info.IPType = risk.Type
+	c.Proxy.Info.Risk = risk.Score
+}
@@ -0,0 +1,112 @@
+package checker
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// writeAsnDb writes a GeoLite2-ASN like fixture mapping each network to an
+// AS number and organization.
+func writeAsnDb(t *testing.T, networks map[string]mmdbtype.Map) string {
+	t.Helper()
+	writer, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "GeoLite2-ASN", RecordSize: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cidr, record := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Insert(network, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "asn.mmdb")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := writer.WriteTo(file); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func asn(number uint32, org string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(number),
+		"autonomous_system_organization": mmdbtype.String(org),
+	}
+}
+
+func TestClassifyIP(t *testing.T) {
+	path := writeAsnDb(t, map[string]mmdbtype.Map{
+		"1.0.0.0/24": asn(16509, "AMAZON-02"),
+		"1.0.1.0/24": asn(9605, "NTT DOCOMO, INC."),
+		"1.0.2.0/24": asn(4713, "NTT Communications Corporation"),
+		"1.0.3.0/24": asn(64500, "Example Broadband"),
+		"1.0.4.0/24": asn(64501, "Example Networks"),
+		"1.0.5.0/24": asn(64502, "Jiotto Telecom Csla It7x"),
+		"1.0.6.0/24": asn(55836, "Reliance Jio Infocomm Limited"),
+	})
+	if err := OpenAsnDb(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(CloseAsnDb)
+	saved := config.GlobalConfig.Risk
+	t.Cleanup(func() { config.GlobalConfig.Risk = saved })
+	config.GlobalConfig.Risk.Datacenter = []string{"Example Networks"}
+	config.GlobalConfig.Risk.Mobile = []string{"broadband"}
+
+	tests := []struct {
+		ip       string
+		wantType string
+		score    int
+	}{
+		{"1.0.0.1", IPTypeDatacenter, 80},
+		{"1.0.1.1", IPTypeMobile, 10},
+		{"1.0.2.1", IPTypeResidential, 20},
+		{"1.0.3.1", IPTypeMobile, 10},
+		{"1.0.4.1", IPTypeDatacenter, 80},
+		{"1.0.5.1", IPTypeResidential, 20},
+		{"1.0.6.1", IPTypeMobile, 10},
+		{"8.8.8.8", "", riskUnknown},
+	}
+	for _, tt := range tests {
+		risk, err := ClassifyIP(net.ParseIP(tt.ip))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.ip, err)
+		}
+		if risk.Type != tt.wantType || risk.Score != tt.score {
+			t.Errorf("%s (%s) = %s/%d, want %s/%d", tt.ip, risk.AsOrg, risk.Type, risk.Score, tt.wantType, tt.score)
+		}
+	}
+}
+
+func TestMatchKeyword(t *testing.T) {
+	tests := []struct {
+		org  string
+		want bool
+	}{
+		{"csl limited", true},
+		{"pccw-hkt csl", true},
+		{"t-mobile usa", true},
+		{"at&t mobility llc", true},
+		{"forcslink networks", false},
+		{"wit7 telecom", false},
+		{"ninjio broadband", false},
+	}
+	for _, tt := range tests {
+		if got := matchKeyword(tt.org, mobileKeywords, datacenterKeywords); got != tt.want {
+			t.Errorf("matchKeyword(%q) = %v, want %v", tt.org, got, tt.want)
+		}
+	}
+}
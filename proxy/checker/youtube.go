@@ -4,11 +4,23 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"regexp"
 	"strings"
+
+	"github.com/bestruirui/bestsub/proxy/info"
 )
 
+func init() {
+	Register(NewPlugin("youtube", func(c *Checker) bool {
+		c.YoutubeTest()
+		return c.Proxy.Info.Unlock.Youtube.Unlocked()
+	}))
+}
+
+var youtubeRegionRegex = regexp.MustCompile(`"countryCode":"([A-Z]{2})"`)
+
 func (c *Checker) YoutubeTest() {
-	ctx, cancel := context.WithCancel(c.Proxy.Ctx)
+	ctx, cancel := context.WithCancel(c.Ctx)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.youtube.com/premium", nil)
@@ -25,7 +37,7 @@ func (c *Checker) YoutubeTest() {
 	req.Header.Set("sec-fetch-site", "none")
 	req.Header.Set("user-agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36")
 
-	resp, err := c.Proxy.Client.Do(req)
+	resp, err := c.Client.Do(req)
 	if err != nil {
 		return
 	}
@@ -35,11 +47,14 @@ func (c *Checker) YoutubeTest() {
 	if err != nil {
 		return
 	}
-	idx := strings.Index(string(body), `"countryCode"`)
-	if idx != -1 {
-		region := strings.Replace(string(body)[idx:idx+17], `"countryCode":"`, "", 1)
-		if region != "" {
-			c.Proxy.Info.Unlock.Youtube = true
-		}
+	region := ""
+	if match := youtubeRegionRegex.FindStringSubmatch(string(body)); match != nil {
+		region = match[1]
+	}
+	switch {
+	case strings.Contains(string(body), "Premium is not available in your country"):
+		c.Proxy.Info.Unlock.Youtube.Set(info.UnlockBlocked, region)
+	case region != "":
+		c.Proxy.Info.Unlock.Youtube.Set(info.UnlockFull, region)
 	}
 }
@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"github.com/bestruirui/bestsub/proxy/info"
+	"github.com/spf13/cast"
+)
+
+// fields lists the proxy fields that can be used in filter expressions.
+var fields = map[string]func(p *info.Proxy) any{
+	"name":    func(p *info.Proxy) any { return cast.ToString(p.Raw["name"]) },
+	"type":    func(p *info.Proxy) any { return cast.ToString(p.Raw["type"]) },
+	"server":  func(p *info.Proxy) any { return cast.ToString(p.Raw["server"]) },
+	"port":    func(p *info.Proxy) any { return cast.ToFloat64(p.Raw["port"]) },
+	"sub":     func(p *info.Proxy) any { return p.SubUrl },
+	"alias":   func(p *info.Proxy) any { return p.SubAlias },
+	"country": func(p *info.Proxy) any { return p.Info.Country },
+	"alive":   func(p *info.Proxy) any { return p.Info.Alive },
+	"speed":   func(p *info.Proxy) any { return float64(p.Info.Speed) },
+	"delay":   func(p *info.Proxy) any { return float64(p.Info.Delay) },
+	"rate":    func(p *info.Proxy) any { return float64(p.Info.Rate) },
+	"risk":    func(p *info.Proxy) any { return float64(p.Info.Risk) },
+	"uptime":  func(p *info.Proxy) any { return float64(p.Info.Uptime) },
+	"jitter":  func(p *info.Proxy) any { return float64(p.Info.Jitter) },
+	"tags":    func(p *info.Proxy) any { return p.Info.Tags },
+	"ip":      func(p *info.Proxy) any { return p.Info.IP },
+	"ip-type": func(p *info.Proxy) any { return p.Info.IPType },
+	"asn":     func(p *info.Proxy) any { return float64(p.Info.Asn) },
+	"as-org":  func(p *info.Proxy) any { return p.Info.AsOrg },
+
+	"unlock.google":     func(p *info.Proxy) any { return p.Info.Unlock.Google },
+	"unlock.cloudflare": func(p *info.Proxy) any { return p.Info.Unlock.Cloudflare },
+}
+
+func init() {
+	unlocks := map[string]func(p *info.Proxy) info.UnlockResult{
+		"openai":  func(p *info.Proxy) info.UnlockResult { return p.Info.Unlock.Chatgpt },
+		"chatgpt": func(p *info.Proxy) info.UnlockResult { return p.Info.Unlock.Chatgpt },
+		"netflix": func(p *info.Proxy) info.UnlockResult { return p.Info.Unlock.Netflix },
+		"disney":  func(p *info.Proxy) info.UnlockResult { return p.Info.Unlock.Disney },
+		"youtube": func(p *info.Proxy) info.UnlockResult { return p.Info.Unlock.Youtube },
+	}
+	for name, get := range unlocks {
+		fields["unlock."+name] = func(p *info.Proxy) any { return get(p).Unlocked() }
+		fields["unlock."+name+".level"] = func(p *info.Proxy) any { return get(p).Level }
+		fields["unlock."+name+".region"] = func(p *info.Proxy) any { return get(p).Region }
+	}
+}
+
+// checkField reads the result of a check plugin, check items are registered
+// at runtime so any name is accepted and an unknown one is simply false.
+func checkField(item string) func(p *info.Proxy) any {
+	return func(p *info.Proxy) any { return p.Info.Checks[item] }
+}
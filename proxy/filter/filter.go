@@ -0,0 +1,372 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bestruirui/bestsub/proxy/info"
+	"github.com/dlclark/regexp2"
+	"github.com/spf13/cast"
+)
+
+// Expr is a compiled filter expression such as
+// "country in [JP] and speed > 2048 and unlock.netflix".
+type Expr struct {
+	source string
+	root   node
+}
+
+type node interface {
+	eval(p *info.Proxy) any
+}
+
+type (
+	fieldNode struct {
+		name string
+		get  func(p *info.Proxy) any
+	}
+	literalNode struct{ value any }
+	listNode    struct{ values []any }
+	notNode     struct{ operand node }
+	logicNode   struct {
+		and         bool
+		left, right node
+	}
+	compareNode struct {
+		op          string
+		left, right node
+	}
+	inNode struct {
+		negate  bool
+		operand node
+		list    listNode
+	}
+	matchNode struct {
+		operand node
+		re      *regexp2.Regexp
+	}
+)
+
+// Compile parses a filter expression, unknown fields and syntax errors are
+// reported with their position.
+func Compile(source string) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+func (e *Expr) Match(p *info.Proxy) bool {
+	return truthy(e.root.eval(p))
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(words ...string) bool {
+	tok := p.peek()
+	for _, word := range words {
+		if (tok.kind == tokenIdent || tok.kind == tokenOp) && strings.EqualFold(tok.text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or", "||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and", "&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isKeyword("not", "!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand(true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isKeyword("in"):
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{operand: left, list: list}, nil
+	case p.isKeyword("not"):
+		p.next()
+		if !p.isKeyword("in") {
+			tok := p.peek()
+			return nil, fmt.Errorf("expected \"in\" after \"not\" at position %d", tok.pos)
+		}
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{negate: true, operand: left, list: list}, nil
+	case p.isKeyword("contains"):
+		p.next()
+		right, err := p.parseOperand(false)
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: "contains", left: left, right: right}, nil
+	case p.isKeyword("=~"):
+		p.next()
+		tok := p.next()
+		if tok.kind != tokenString && tok.kind != tokenIdent {
+			return nil, fmt.Errorf("expected regular expression at position %d", tok.pos)
+		}
+		re, err := regexp2.Compile(tok.text, regexp2.IgnoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %w", tok.pos, err)
+		}
+		return matchNode{operand: left, re: re}, nil
+	case p.isKeyword("==", "=", "!=", ">", ">=", "<", "<="):
+		op := p.next().text
+		if op == "=" {
+			op = "=="
+		}
+		right, err := p.parseOperand(false)
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+// parseOperand parses a value. A bare word must be a known field on the left
+// hand side and is taken as a string on the right hand side.
+func (p *parser) parseOperand(left bool) (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d", closing.pos)
+		}
+		return expr, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literalNode{value: value}, nil
+	case tokenString:
+		return literalNode{value: tok.text}, nil
+	case tokenIdent:
+		name := strings.ToLower(tok.text)
+		switch name {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		}
+		if get, ok := fields[name]; ok {
+			return fieldNode{name: name, get: get}, nil
+		}
+		if item, ok := strings.CutPrefix(name, "check."); ok && item != "" {
+			return fieldNode{name: name, get: checkField(item)}, nil
+		}
+		if left {
+			return nil, fmt.Errorf("unknown field %q at position %d", tok.text, tok.pos)
+		}
+		return literalNode{value: tok.text}, nil
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseList() (listNode, error) {
+	if tok := p.next(); tok.kind != tokenLBracket {
+		return listNode{}, fmt.Errorf("expected \"[\" at position %d", tok.pos)
+	}
+	list := listNode{}
+	for {
+		tok := p.next()
+		switch tok.kind {
+		case tokenRBracket:
+			return list, nil
+		case tokenNumber:
+			value, err := strconv.ParseFloat(tok.text, 64)
+			if err != nil {
+				return listNode{}, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+			}
+			list.values = append(list.values, value)
+		case tokenString, tokenIdent:
+			list.values = append(list.values, tok.text)
+		default:
+			return listNode{}, fmt.Errorf("unexpected %q in list at position %d", tok.text, tok.pos)
+		}
+		switch sep := p.next(); sep.kind {
+		case tokenComma:
+		case tokenRBracket:
+			return list, nil
+		default:
+			return listNode{}, fmt.Errorf("expected \",\" or \"]\" at position %d", sep.pos)
+		}
+	}
+}
+
+func (n fieldNode) eval(p *info.Proxy) any   { return n.get(p) }
+func (n literalNode) eval(p *info.Proxy) any { return n.value }
+func (n listNode) eval(p *info.Proxy) any    { return n.values }
+func (n notNode) eval(p *info.Proxy) any     { return !truthy(n.operand.eval(p)) }
+
+func (n logicNode) eval(p *info.Proxy) any {
+	if n.and {
+		return truthy(n.left.eval(p)) && truthy(n.right.eval(p))
+	}
+	return truthy(n.left.eval(p)) || truthy(n.right.eval(p))
+}
+
+func (n inNode) eval(p *info.Proxy) any {
+	value := n.operand.eval(p)
+	found := false
+	for _, item := range n.list.values {
+		if equal(value, item) {
+			found = true
+			break
+		}
+	}
+	return found != n.negate
+}
+
+func (n matchNode) eval(p *info.Proxy) any {
+	match, err := n.re.MatchString(cast.ToString(n.operand.eval(p)))
+	return err == nil && match
+}
+
+func (n compareNode) eval(p *info.Proxy) any {
+	left, right := n.left.eval(p), n.right.eval(p)
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "contains":
+		if items, ok := left.([]string); ok {
+			for _, item := range items {
+				if equal(item, right) {
+					return true
+				}
+			}
+			return false
+		}
+		return strings.Contains(strings.ToLower(cast.ToString(left)), strings.ToLower(cast.ToString(right)))
+	}
+	l, lerr := cast.ToFloat64E(left)
+	r, rerr := cast.ToFloat64E(right)
+	if lerr != nil || rerr != nil {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	}
+	return false
+}
+
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case float64:
+		bv, err := cast.ToFloat64E(b)
+		return err == nil && av == bv
+	case bool:
+		bv, err := cast.ToBoolE(b)
+		return err == nil && av == bv
+	}
+	return strings.EqualFold(cast.ToString(a), cast.ToString(b))
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	}
+	return value != nil
+}
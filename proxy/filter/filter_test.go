@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/bestsub/proxy/info"
+)
+
+func testProxy() *info.Proxy {
+	p := &info.Proxy{
+		Raw:    map[string]any{"name": "🇯🇵 Tokyo 01", "type": "vmess", "server": "1.2.3.4", "port": 443},
+		SubUrl: "https://example.com/sub",
+		Info: info.ProxyInfo{
+			Alive:   true,
+			Country: "JP",
+			Speed:   4096,
+			Delay:   150,
+			Tags:    []string{"residential", "stable"},
+			Checks:  map[string]bool{"ping": true},
+		},
+	}
+	p.Info.Unlock.Netflix.Set(info.UnlockFull, "jp")
+	return p
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// precedence: not > and > or
+		{"alive or country == US and speed > 99999", true},
+		{"(alive or country == US) and speed > 99999", false},
+		{"not alive or country == JP", true},
+		{"not (alive or country == US)", false},
+		{"! alive || alive && country == JP", true},
+		{"not not alive", true},
+		{"((country == JP))", true},
+
+		// strings are compared case-insensitively
+		{"country == jp", true},
+		{`country = "JP"`, true},
+		{"country != JP", false},
+		{"type == 'VMESS'", true},
+		{"country in [US, JP]", true},
+		{"country not in [US, 'JP']", false},
+		{"name contains tokyo", true},
+		{"tags contains stable", true},
+		{"tags contains stab", false},
+
+		// numbers
+		{"speed > 4095", true},
+		{"speed >= 4096", true},
+		{"speed < 4096", false},
+		{"delay <= 150.0", true},
+		{"port == 443", true},
+		{"port in [80, 443]", true},
+		{"delay > -1", true},
+		{"country > 1", false},
+
+		// booleans
+		{"alive == true", true},
+		{"alive == false", false},
+		{"alive != true", false},
+		{"unlock.netflix", true},
+		{"unlock.disney", false},
+		{"unlock.netflix.level == full", true},
+		{"unlock.netflix.region == JP", true},
+		{"check.ping", true},
+		{"check.unknown", false},
+
+		// regular expressions
+		{`name =~ "tokyo\s+\d+"`, true},
+		{`name =~ "^osaka"`, false},
+		{"server =~ '^1\\.2\\.'", true},
+	}
+	p := testProxy()
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Match(p); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"countri == JP", `unknown field "countri" at position 0`},
+		{"alive and speeed > 1", `unknown field "speeed" at position 10`},
+		{"(alive", `expected ")" at position 6`},
+		{"alive)", `unexpected ")" at position 5`},
+		{"country in JP", `expected "[" at position 11`},
+		{"country in [JP US]", `expected "," or "]" at position 15`},
+		{"country not JP", `expected "in" after "not" at position 12`},
+		{"name == 'abc", "unterminated string at position 8"},
+		{"speed > 1 # x", `unexpected character '#' at position 10`},
+		{`name =~ "(["`, "invalid regular expression at position 8"},
+		{"name =~", "expected regular expression at position 7"},
+		{"alive and", "unexpected end of expression"},
+		{"", "unexpected end of expression"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		if err == nil {
+			t.Errorf("Compile(%q): expected error", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) = %q, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`speed>=1.5&&name=~'a\'b'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []token{
+		{tokenIdent, "speed", 0},
+		{tokenOp, ">=", 5},
+		{tokenNumber, "1.5", 7},
+		{tokenOp, "&&", 10},
+		{tokenIdent, "name", 12},
+		{tokenOp, "=~", 16},
+		{tokenString, "a'b", 18},
+		{tokenEOF, "", 24},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("tokens = %v, want %v", tokens, want)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("token %d = %v, want %v", i, tokens[i], want[i])
+		}
+	}
+}
+
+func TestNetflixOriginals(t *testing.T) {
+	p := testProxy()
+	p.Info.Unlock.Netflix.Set(info.UnlockOriginals, "jp")
+	tests := map[string]bool{
+		"unlock.netflix":                    false,
+		"unlock.netflix.level == originals": true,
+		"unlock.netflix.region == JP":       true,
+	}
+	for source, want := range tests {
+		expr, err := Compile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.Match(p); got != want {
+			t.Errorf("%q = %v, want %v", source, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"==", "!=", ">=", "<=", "=~", "&&", "||", ">", "<", "!", "="}
+
+func tokenize(input string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{tokenLBracket, "[", i})
+			i++
+		case r == ']':
+			tokens = append(tokens, token{tokenRBracket, "]", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != r {
+				// only the quote is escaped, other backslashes are kept for regular expressions
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == r {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, sb.String(), start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start})
+		case isIdentRune(r):
+			start := i
+			for i < len(runes) && (isIdentRune(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{tokenOp, op, i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	tokens = append(tokens, token{tokenEOF, "", len(runes)})
+	return tokens, nil
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == '.'
+}
@@ -6,7 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -18,7 +19,9 @@ import (
 	"github.com/bestruirui/bestsub/proxy/parser"
 	"github.com/bestruirui/bestsub/utils"
 	"github.com/bestruirui/bestsub/utils/log"
+	"github.com/dlclark/regexp2"
 	"github.com/panjf2000/ants/v2"
+	"github.com/spf13/cast"
 	"gopkg.in/yaml.v3"
 )
 
@@ -31,17 +34,28 @@ func GetProxies(proxies *[]info.Proxy) {
 	pool, _ := ants.NewPool(numWorkers)
 	defer pool.Release()
 	var wg sync.WaitGroup
-	for _, subUrl := range config.GlobalConfig.SubUrls {
+	// every subscription fills its own slot, so the proxies are joined in
+	// config order whatever order the fetches finish in
+	subProxies := make([][]info.Proxy, len(config.GlobalConfig.SubUrls))
+	for i, sub := range config.GlobalConfig.SubUrls {
+		if skipMuted(sub.Url) {
+			log.Info("subscription link [%s] is muted, skip", sub.Url)
+			continue
+		}
 		wg.Add(1)
-		// copy subUrl to a new variable
-		subUrl := subUrl
+		// copy sub to a new variable
+		i, sub := i, sub
 		pool.Submit(func() {
 			defer wg.Done()
-			processedUrl := replaceDateTimePlaceholders(subUrl)
-			taskGetProxies(processedUrl, proxies)
+			processedUrl := replaceDateTimePlaceholders(sub.Url)
+			count, err := taskGetProxies(sub, processedUrl, &subProxies[i])
+			setSubResult(sub, processedUrl, count, err)
 		})
 	}
 	wg.Wait()
+	for _, list := range subProxies {
+		*proxies = append(*proxies, list...)
+	}
 }
 
 func replaceDateTimePlaceholders(url string) string {
@@ -57,60 +71,192 @@ func replaceDateTimePlaceholders(url string) string {
 	return r.Replace(url)
 }
 
-func taskGetProxies(args string, proxiesInfo *[]info.Proxy) {
+func taskGetProxies(sub config.SubConfig, args string, proxiesInfo *[]info.Proxy) (count int, fetchErr error) {
 
-	data, err := getDateFromSubs(args)
-	if err != nil {
-		log.Warn("subscription link [%s] get data failed: %v", args, err)
-		return
+	var contents [][]byte
+	if isLocalSub(args) {
+		files, err := readLocalSub(args)
+		if err != nil {
+			log.Warn("subscription link [%s] read local files failed: %v", args, err)
+			return 0, err
+		}
+		contents = files
+	} else {
+		cached := loadSubCache(sub.Url)
+		data, fresh, err := getDateFromSubs(sub, args, cached)
+		if err != nil {
+			if cached == nil {
+				log.Warn("subscription link [%s] get data failed: %v", args, err)
+				return 0, err
+			}
+			log.Warn("subscription link [%s] get data failed, using the cached copy from %s: %v", args, cached.Time.Format(time.DateTime), err)
+			fetchErr = fmt.Errorf("%w, using the cached copy from %s", err, cached.Time.Format(time.DateTime))
+			data = cached.body
+		}
+		contents = [][]byte{data}
+		defer func() {
+			// only a body that yields proxies replaces the last good copy
+			if fresh != nil && count > 0 {
+				if err := saveSubCache(sub.Url, fresh); err != nil {
+					log.Warn("subscription link [%s] save cache failed: %v", args, err)
+				}
+			}
+		}()
+	}
+
+	subProxies := make([]info.Proxy, 0)
+	for _, data := range contents {
+		if err := parseSubData(sub, data, args, nil, &subProxies); err != nil && len(contents) == 1 {
+			return 0, err
+		}
 	}
+	subProxies = filterSubProxies(sub, subProxies)
+	for i := range subProxies {
+		subProxies[i].SubAlias = sub.Alias
+	}
+	mihomoProxiesMutex.Lock()
+	*proxiesInfo = append(*proxiesInfo, subProxies...)
+	mihomoProxiesMutex.Unlock()
+	return len(subProxies), fetchErr
+}
+
+// parseSubData detects the format of a subscription body and appends its
+// proxies, providers are the urls of the proxy-providers followed to reach
+// the body.
+func parseSubData(sub config.SubConfig, data []byte, args string, providers []string, subProxies *[]info.Proxy) error {
 	if IsYaml(data, args) {
-		err := ParseYamlProxy(data, proxiesInfo, args)
+		err := parseYamlConfig(sub, data, args, providers, subProxies)
 		if err != nil {
 			log.Warn("subscription link [%s] has no proxies", args)
-			return
+			return err
 		}
-	} else {
-		reg, _ := regexp.Compile(`^(ssr://|ss://|vmess://|trojan://|vless://|hysteria://|hy2://|hysteria2://)`)
-		if !reg.Match(data) {
-			log.Debug("subscription link [%s] is not a v2ray subscription link, attempting to decode the subscription link using base64", args)
-			data = []byte(parser.DecodeBase64(string(data)))
-		}
-		if reg.Match(data) {
-			proxies := strings.Split(string(data), "\n")
-
-			for _, proxy := range proxies {
-				parseProxy, err := parser.ParseProxy(proxy)
-				if err != nil {
-					continue
-				}
-				if parseProxy == nil {
-					continue
-				}
-				if len(config.GlobalConfig.TypeInclude) > 0 {
-					for _, t := range config.GlobalConfig.TypeInclude {
-						if t == parseProxy["type"].(string) {
-							mihomoProxiesMutex.Lock()
-							*proxiesInfo = append(*proxiesInfo, info.Proxy{Raw: parseProxy, SubUrl: args})
-							mihomoProxiesMutex.Unlock()
-							break
-						}
-					}
-				} else {
-					mihomoProxiesMutex.Lock()
-					*proxiesInfo = append(*proxiesInfo, info.Proxy{Raw: parseProxy, SubUrl: args})
-					mihomoProxiesMutex.Unlock()
-				}
+		return nil
+	}
+	if parser.IsSip008(data) {
+		proxies, errs := parser.ParseSip008(data)
+		for _, err := range errs {
+			log.Warn("subscription link [%s] %v", args, err)
+		}
+		for _, proxy := range proxies {
+			*subProxies = append(*subProxies, info.Proxy{Raw: proxy, SubUrl: args})
+		}
+		return nil
+	}
+	if parser.IsOutbounds(data) {
+		proxies, errs := parser.ParseOutbounds(data)
+		for _, err := range errs {
+			log.Warn("subscription link [%s] %v", args, err)
+		}
+		for _, proxy := range proxies {
+			*subProxies = append(*subProxies, info.Proxy{Raw: proxy, SubUrl: args})
+		}
+		return nil
+	}
+	if !parser.IsProxyLink(data) {
+		log.Debug("subscription link [%s] is not a v2ray subscription link, attempting to decode the subscription link using base64", args)
+		data = []byte(parser.DecodeBase64(string(data)))
+	}
+	if parser.IsProxyLink(data) {
+		proxies := strings.Split(string(data), "\n")
+
+		for _, proxy := range proxies {
+			parseProxy, err := parser.ParseProxy(proxy)
+			if err != nil {
+				continue
+			}
+			if parseProxy == nil {
+				continue
+			}
+			*subProxies = append(*subProxies, info.Proxy{Raw: parseProxy, SubUrl: args})
+		}
+	}
+	return nil
+}
+
+// filterSubProxies applies the type-include of the subscription, or the
+// global one, and its include and exclude name patterns.
+func filterSubProxies(sub config.SubConfig, proxies []info.Proxy) []info.Proxy {
+	typeInclude := config.GlobalConfig.TypeInclude
+	if len(sub.TypeInclude) > 0 {
+		typeInclude = sub.TypeInclude
+	}
+	// 正则已在启动时校验
+	var include, exclude *regexp2.Regexp
+	if sub.Include != "" {
+		include, _ = regexp2.Compile(sub.Include, regexp2.None)
+	}
+	if sub.Exclude != "" {
+		exclude, _ = regexp2.Compile(sub.Exclude, regexp2.None)
+	}
+
+	filtered := proxies[:0]
+	for _, p := range proxies {
+		if len(typeInclude) > 0 && !slices.Contains(typeInclude, cast.ToString(p.Raw["type"])) {
+			continue
+		}
+		name := cast.ToString(p.Raw["name"])
+		if include != nil {
+			if ok, _ := include.MatchString(name); !ok {
+				continue
+			}
+		}
+		if exclude != nil {
+			if ok, _ := exclude.MatchString(name); ok {
+				continue
+			}
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
 
+// ValiSubs checks the settings of every subscription.
+func ValiSubs() error {
+	seen := make(map[string]bool)
+	for _, sub := range config.GlobalConfig.SubUrls {
+		if sub.Url == "" {
+			return fmt.Errorf("subscription url is empty")
+		}
+		if seen[sub.Url] {
+			return fmt.Errorf("subscription %s is listed twice", sub.Url)
+		}
+		seen[sub.Url] = true
+		if isLocalSub(sub.Url) {
+			if _, err := filepath.Match(localPattern(sub.Url), ""); err != nil {
+				return fmt.Errorf("subscription %s: invalid file pattern: %w", sub.Url, err)
+			}
+		}
+		if sub.Include != "" {
+			if _, err := regexp2.Compile(sub.Include, regexp2.None); err != nil {
+				return fmt.Errorf("subscription %s: invalid include: %w", sub.Url, err)
+			}
+		}
+		if sub.Exclude != "" {
+			if _, err := regexp2.Compile(sub.Exclude, regexp2.None); err != nil {
+				return fmt.Errorf("subscription %s: invalid exclude: %w", sub.Url, err)
 			}
 		}
 	}
+	return nil
 }
 
-func getDateFromSubs(subUrl string) ([]byte, error) {
+// getDateFromSubs downloads a subscription. A cached copy of the same url is
+// revalidated with its ETag and Last-Modified and reused on 304, fresh is the
+// entry to cache and nil when the cached copy was reused.
+func getDateFromSubs(sub config.SubConfig, subUrl string, cached *subCacheEntry) (body []byte, fresh *subCacheEntry, err error) {
 	var lastErr error
 	client := utils.NewHTTPClient()
+	if sub.Direct {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
 	maxRetries := config.GlobalConfig.SubUrlsReTry
+	if sub.Retry > 0 {
+		maxRetries = sub.Retry
+	}
+	userAgent := "clash.meta"
+	if sub.UserAgent != "" {
+		userAgent = sub.UserAgent
+	}
 
 	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
@@ -123,7 +269,18 @@ func getDateFromSubs(subUrl string) ([]byte, error) {
 			continue
 		}
 
-		req.Header.Set("User-Agent", "clash.meta")
+		req.Header.Set("User-Agent", userAgent)
+		for key, value := range sub.Headers {
+			req.Header.Set(key, value)
+		}
+		if cached != nil && cached.Url == subUrl {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
 		req.Close = true
 
 		resp, err := client.Do(req)
@@ -131,6 +288,14 @@ func getDateFromSubs(subUrl string) ([]byte, error) {
 			lastErr = err
 			continue
 		}
+		if sub.Url != "" && (resp.StatusCode == 200 || resp.StatusCode == http.StatusNotModified) {
+			recordSubUserInfo(sub.Url, resp.Header.Get("Subscription-Userinfo"))
+		}
+		if resp.StatusCode == http.StatusNotModified && cached != nil && cached.Url == subUrl {
+			resp.Body.Close()
+			log.Debug("subscription link [%s] not modified, using the cached copy", subUrl)
+			return cached.body, nil, nil
+		}
 		if resp.StatusCode != 200 {
 			resp.Body.Close()
 			lastErr = fmt.Errorf("subscription link [%s] returned status code: %d", subUrl, resp.StatusCode)
@@ -143,10 +308,16 @@ func getDateFromSubs(subUrl string) ([]byte, error) {
 			lastErr = err
 			continue
 		}
-		return body, nil
+		return body, &subCacheEntry{
+			Url:          subUrl,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Time:         time.Now(),
+			body:         body,
+		}, nil
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %v", maxRetries, lastErr)
+	return nil, nil, fmt.Errorf("failed after %d retries: %v", maxRetries, lastErr)
 }
 func removeAllControlCharacters(data []byte) []byte {
 	var cleanedData []byte
@@ -161,15 +332,13 @@ func removeAllControlCharacters(data []byte) []byte {
 }
 
 func IsYaml(data []byte, subUrl string) bool {
-	reg, _ := regexp.Compile(`^(ssr://|ss://|vmess://|trojan://|vless://|hysteria://|hy2://|hysteria2://)`)
-
 	decodedData := parser.DecodeBase64(string(data))
-	if reg.MatchString(decodedData) {
+	if parser.IsProxyLink([]byte(decodedData)) {
 		log.Debug("subscription link [%s] is a v2ray subscription link", subUrl)
 		return false
 	}
 
-	if bytes.Contains(data, []byte("proxies:")) {
+	if bytes.Contains(data, []byte("proxies:")) || bytes.Contains(data, []byte("proxy-providers:")) {
 		log.Debug("subscription link [%s] is a yaml file", subUrl)
 		return true
 	}
@@ -185,35 +354,21 @@ func appendParsedYamlProxy(proxyData []map[string]any, proxies *[]info.Proxy, su
 		return
 	}
 
-	if len(config.GlobalConfig.TypeInclude) > 0 {
-		for _, t := range config.GlobalConfig.TypeInclude {
-			if t == proxyType {
-				mihomoProxiesMutex.Lock()
-				*proxies = append(*proxies, info.Proxy{Raw: proxyData[0], SubUrl: subUrl})
-				mihomoProxiesMutex.Unlock()
-				return
-			}
-		}
-		return
-	}
-
 	mihomoProxiesMutex.Lock()
 	*proxies = append(*proxies, info.Proxy{Raw: proxyData[0], SubUrl: subUrl})
 	mihomoProxiesMutex.Unlock()
 }
 
-func flushYamlBuffer(yamlBuffer *bytes.Buffer, proxies *[]info.Proxy, subUrl string, lineNum int, isRemaining bool) {
+// flushYamlBuffer decodes the proxy in the buffer, startLine is the line the
+// proxy starts at.
+func flushYamlBuffer(yamlBuffer *bytes.Buffer, proxies *[]info.Proxy, subUrl string, startLine int) {
 	if yamlBuffer.Len() == 0 {
 		return
 	}
 
 	var proxyData []map[string]any
 	if err := yaml.Unmarshal(yamlBuffer.Bytes(), &proxyData); err != nil {
-		if isRemaining {
-			log.Warn("Failed to unmarshal remaining YAML proxy from sub [%s]: %v. Buffer content: %s", subUrl, err, yamlBuffer.String())
-		} else {
-			log.Warn("Failed to unmarshal YAML proxy from sub [%s] at line %d: %v. Buffer content: %s", subUrl, lineNum, err, yamlBuffer.String())
-		}
+		log.Warn("Failed to unmarshal YAML proxy from sub [%s] at line %d: %v. Buffer content: %s", subUrl, startLine, err, yamlBuffer.String())
 		yamlBuffer.Reset()
 		return
 	}
@@ -222,12 +377,15 @@ func flushYamlBuffer(yamlBuffer *bytes.Buffer, proxies *[]info.Proxy, subUrl str
 	yamlBuffer.Reset()
 }
 
-func ParseYamlProxy(data []byte, proxies *[]info.Proxy, subUrl string) error {
-	log.Debug("Entering ParseYamlProxy for subUrl: %s", subUrl)
+// parseYamlLines scans the proxies section line by line, it is used for
+// documents that are not valid yaml as a whole.
+func parseYamlLines(data []byte, proxies *[]info.Proxy, subUrl string) error {
+	log.Debug("Entering parseYamlLines for subUrl: %s", subUrl)
 	var inProxiesSection bool
 	var yamlBuffer bytes.Buffer
 	var indent int
 	var isFirst bool = true
+	var startLine int
 
 	cleandata := removeAllControlCharacters(data)
 	cleanedFile := bytes.NewReader(cleandata)
@@ -240,7 +398,7 @@ func ParseYamlProxy(data []byte, proxies *[]info.Proxy, subUrl string) error {
 		trimmedLine := strings.TrimSpace(line)
 		log.Debug("Processing line %d: %s", lineNum, trimmedLine)
 
-		if trimmedLine == "proxies:" {
+		if isProxiesHeader(trimmedLine) {
 			inProxiesSection = true
 			log.Debug("Found 'proxies:' section at line %d", lineNum)
 			continue
@@ -269,9 +427,10 @@ func ParseYamlProxy(data []byte, proxies *[]info.Proxy, subUrl string) error {
 		if strings.HasPrefix(trimmedLine, "-") && len(line)-len(trimmedLine) == indent {
 			if yamlBuffer.Len() > 0 {
 				log.Debug("Attempting to unmarshal YAML buffer at line %d. Buffer size: %d", lineNum, yamlBuffer.Len())
-				flushYamlBuffer(&yamlBuffer, proxies, subUrl, lineNum, false)
+				flushYamlBuffer(&yamlBuffer, proxies, subUrl, startLine)
 				log.Debug("YAML buffer reset.")
 			}
+			startLine = lineNum
 			yamlBuffer.WriteString(line + "\n")
 			log.Debug("Added line %d to YAML buffer. Current buffer size: %d", lineNum, yamlBuffer.Len())
 		} else if yamlBuffer.Len() > 0 {
@@ -282,8 +441,18 @@ func ParseYamlProxy(data []byte, proxies *[]info.Proxy, subUrl string) error {
 
 	if yamlBuffer.Len() > 0 {
 		log.Debug("Attempting to unmarshal remaining YAML buffer after loop. Buffer size: %d", yamlBuffer.Len())
-		flushYamlBuffer(&yamlBuffer, proxies, subUrl, lineNum, true)
+		flushYamlBuffer(&yamlBuffer, proxies, subUrl, startLine)
 	}
-	log.Debug("Exiting ParseYamlProxy for subUrl: %s", subUrl)
+	log.Debug("Exiting parseYamlLines for subUrl: %s", subUrl)
 	return nil
 }
+
+// isProxiesHeader matches "proxies:" with an optional trailing comment.
+func isProxiesHeader(line string) bool {
+	rest, ok := strings.CutPrefix(line, "proxies:")
+	if !ok {
+		return false
+	}
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
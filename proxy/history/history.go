@@ -0,0 +1,278 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+	"github.com/metacubex/bbolt"
+)
+
+const (
+	defaultKeep = 30
+	staleAfter  = 7 * 24 * time.Hour
+)
+
+var nodesBucket = []byte("nodes")
+
+// Sample is the result of one check run for a single node.
+type Sample struct {
+	Time   int64       `json:"time"`
+	Alive  bool        `json:"alive"`
+	Delay  uint16      `json:"delay,omitempty"`
+	Speed  int         `json:"speed,omitempty"`
+	Unlock info.Unlock `json:"unlock"`
+	// CheckedAt is the run the results were checked in, it is older than
+	// Time when incremental mode reused them
+	CheckedAt int64           `json:"checked-at,omitempty"`
+	Country   string          `json:"country,omitempty"`
+	Checks    map[string]bool `json:"checks,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	IPType    string          `json:"ip-type,omitempty"`
+	Asn       uint            `json:"asn,omitempty"`
+	AsOrg     string          `json:"as-org,omitempty"`
+	Risk      int             `json:"risk,omitempty"`
+}
+
+// Entry holds the recent samples of a node, oldest first.
+type Entry struct {
+	Name    string   `json:"name"`
+	SubUrl  string   `json:"sub-url"`
+	Samples []Sample `json:"samples"`
+}
+
+var db *bbolt.DB
+
+func Open(path string) error {
+	var err error
+	db, err = bbolt.Open(path, 0644, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		db = nil
+		return fmt.Errorf("open history db failed: %w", err)
+	}
+	return db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(nodesBucket)
+		return err
+	})
+}
+
+func Close() {
+	if db != nil {
+		db.Close()
+		db = nil
+	}
+}
+
+func Enabled() bool {
+	return db != nil
+}
+
+func keep() int {
+	if config.GlobalConfig.History.Keep > 0 {
+		return config.GlobalConfig.History.Keep
+	}
+	return defaultKeep
+}
+
+// Record appends the result of the current run to the history of every node
+// and drops nodes that have not been seen for a week.
+func Record(proxies []info.Proxy, runTime time.Time) error {
+	if db == nil {
+		return nil
+	}
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(nodesBucket)
+		for i := range proxies {
+			key := []byte(proxies[i].Fingerprint())
+			entry := getEntry(bucket, key)
+			entry.Name, _ = proxies[i].Raw["name"].(string)
+			entry.SubUrl = proxies[i].SubUrl
+			checkedAt := runTime.Unix()
+			if proxies[i].Info.Cached && len(entry.Samples) > 0 {
+				checkedAt = entry.Samples[len(entry.Samples)-1].CheckedAt
+			}
+			p := &proxies[i].Info
+			entry.Samples = append(entry.Samples, Sample{
+				Time:      runTime.Unix(),
+				Alive:     p.Alive,
+				Delay:     p.Delay,
+				Unlock:    p.Unlock,
+				CheckedAt: checkedAt,
+				Country:   p.Country,
+				Checks:    p.Checks,
+				Tags:      p.Tags,
+				IP:        p.IP,
+				IPType:    p.IPType,
+				Asn:       p.Asn,
+				AsOrg:     p.AsOrg,
+				Risk:      p.Risk,
+			})
+			if len(entry.Samples) > keep() {
+				entry.Samples = entry.Samples[len(entry.Samples)-keep():]
+			}
+			if err := putEntry(bucket, key, entry); err != nil {
+				return err
+			}
+		}
+		return prune(bucket, runTime.Add(-staleAfter).Unix())
+	})
+}
+
+// RecordSpeed stores the speed test result on the sample of the given run.
+func RecordSpeed(proxies []info.Proxy, runTime time.Time) error {
+	if db == nil {
+		return nil
+	}
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(nodesBucket)
+		for i := range proxies {
+			if proxies[i].Info.Speed == 0 {
+				continue
+			}
+			key := []byte(proxies[i].Fingerprint())
+			entry := getEntry(bucket, key)
+			if len(entry.Samples) == 0 {
+				continue
+			}
+			last := &entry.Samples[len(entry.Samples)-1]
+			if last.Time != runTime.Unix() {
+				continue
+			}
+			last.Speed = proxies[i].Info.Speed
+			if err := putEntry(bucket, key, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Apply fills the uptime and jitter of every node from its history.
+func Apply(proxies []info.Proxy) error {
+	if db == nil {
+		return nil
+	}
+	return db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(nodesBucket)
+		for i := range proxies {
+			entry := getEntry(bucket, []byte(proxies[i].Fingerprint()))
+			proxies[i].Info.Uptime, proxies[i].Info.Jitter = Stability(entry.Samples)
+		}
+		return nil
+	})
+}
+
+// Reuse copies the results of the last full check onto a node that is alive
+// again, when that check found it alive no longer than ttl ago. Only the
+// alive test is run again for such nodes, it reports whether results were
+// reused.
+func Reuse(p *info.Proxy, ttl time.Duration, now time.Time) bool {
+	if db == nil || ttl <= 0 {
+		return false
+	}
+	entry, err := Lookup(p.Fingerprint())
+	if err != nil || len(entry.Samples) == 0 {
+		return false
+	}
+	last := entry.Samples[len(entry.Samples)-1]
+	// 旧版本的记录没有 CheckedAt，无法判断结果是否过期
+	if !last.Alive || last.CheckedAt == 0 || now.Sub(time.Unix(last.CheckedAt, 0)) > ttl {
+		return false
+	}
+
+	p.Info.Unlock = last.Unlock
+	p.Info.Speed = last.Speed
+	p.Info.Country = last.Country
+	p.Info.Checks = last.Checks
+	p.Info.Tags = last.Tags
+	p.Info.IP = last.IP
+	p.Info.IPType = last.IPType
+	p.Info.Asn = last.Asn
+	p.Info.AsOrg = last.AsOrg
+	p.Info.Risk = last.Risk
+	p.Info.Cached = true
+	return true
+}
+
+// Lookup returns the history of a single node.
+func Lookup(fingerprint string) (Entry, error) {
+	var entry Entry
+	if db == nil {
+		return entry, fmt.Errorf("history is not enabled")
+	}
+	err := db.View(func(tx *bbolt.Tx) error {
+		entry = getEntry(tx.Bucket(nodesBucket), []byte(fingerprint))
+		return nil
+	})
+	return entry, err
+}
+
+// Stability returns the uptime percentage of the samples and the jitter, the
+// mean difference in milliseconds between consecutive alive delays.
+func Stability(samples []Sample) (float32, uint16) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	alive := 0
+	var lastDelay, diffSum float64
+	diffCount := 0
+	for _, sample := range samples {
+		if !sample.Alive {
+			continue
+		}
+		if alive > 0 {
+			diffSum += math.Abs(float64(sample.Delay) - lastDelay)
+			diffCount++
+		}
+		lastDelay = float64(sample.Delay)
+		alive++
+	}
+	uptime := float32(alive) * 100 / float32(len(samples))
+	if diffCount == 0 {
+		return uptime, 0
+	}
+	return uptime, uint16(math.Round(diffSum / float64(diffCount)))
+}
+
+func getEntry(bucket *bbolt.Bucket, key []byte) Entry {
+	var entry Entry
+	if data := bucket.Get(key); data != nil {
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return Entry{}
+		}
+	}
+	return entry
+}
+
+func putEntry(bucket *bbolt.Bucket, key []byte, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal history entry failed: %w", err)
+	}
+	return bucket.Put(key, data)
+}
+
+func prune(bucket *bbolt.Bucket, before int64) error {
+	var stale [][]byte
+	err := bucket.ForEach(func(key, data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil || len(entry.Samples) == 0 ||
+			entry.Samples[len(entry.Samples)-1].Time < before {
+			stale = append(stale, append([]byte(nil), key...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+)
+
+func openTestDb(t *testing.T) {
+	t.Helper()
+	if err := Open(filepath.Join(t.TempDir(), "history.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Close)
+}
+
+func fingerprint(server string) string {
+	node := testNode(server, true, 0)
+	return node.Fingerprint()
+}
+
+func testNode(server string, alive bool, delay uint16) info.Proxy {
+	return info.Proxy{
+		Raw:  map[string]any{"name": server, "type": "ss", "server": server, "port": 443},
+		Info: info.ProxyInfo{Alive: alive, Delay: delay},
+	}
+}
+
+func TestStability(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []Sample
+		uptime  float32
+		jitter  uint16
+	}{
+		{"empty", nil, 0, 0},
+		{"single alive", []Sample{{Alive: true, Delay: 100}}, 100, 0},
+		{"all dead", []Sample{{}, {}}, 0, 0},
+		// diffs between alive delays 100 -> 160 -> 130: (60 + 30) / 2
+		{"dead samples skipped", []Sample{{Alive: true, Delay: 100}, {}, {Alive: true, Delay: 160}, {Alive: true, Delay: 130}}, 75, 45},
+		{"rounded", []Sample{{Alive: true, Delay: 100}, {Alive: true, Delay: 101}, {Alive: true, Delay: 103}}, 100, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uptime, jitter := Stability(tt.samples)
+			if uptime != tt.uptime || jitter != tt.jitter {
+				t.Errorf("Stability = %v, %v, want %v, %v", uptime, jitter, tt.uptime, tt.jitter)
+			}
+		})
+	}
+}
+
+func TestRecordKeepAndApply(t *testing.T) {
+	openTestDb(t)
+	saved := config.GlobalConfig.History
+	t.Cleanup(func() { config.GlobalConfig.History = saved })
+	config.GlobalConfig.History.Keep = 3
+
+	start := time.Unix(1700000000, 0)
+	runs := []struct {
+		alive bool
+		delay uint16
+	}{{true, 500}, {false, 0}, {true, 100}, {true, 200}}
+	for i, run := range runs {
+		proxies := []info.Proxy{testNode("1.1.1.1", run.alive, run.delay)}
+		if err := Record(proxies, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	node := testNode("1.1.1.1", true, 0)
+	entry, err := Lookup(node.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Samples) != 3 || entry.Samples[0].Alive {
+		t.Fatalf("samples = %+v, want the last 3 runs", entry.Samples)
+	}
+
+	proxies := []info.Proxy{node}
+	if err := Apply(proxies); err != nil {
+		t.Fatal(err)
+	}
+	if uptime := proxies[0].Info.Uptime; uptime < 66.6 || uptime > 66.7 {
+		t.Errorf("uptime = %v, want 66.67", uptime)
+	}
+	if proxies[0].Info.Jitter != 100 {
+		t.Errorf("jitter = %v, want 100", proxies[0].Info.Jitter)
+	}
+}
+
+func TestRecordPrunesStaleNodes(t *testing.T) {
+	openTestDb(t)
+	start := time.Unix(1700000000, 0)
+	if err := Record([]info.Proxy{testNode("1.1.1.1", true, 100), testNode("2.2.2.2", true, 100)}, start); err != nil {
+		t.Fatal(err)
+	}
+	// only 2.2.2.2 is seen again, within and then past the retention
+	if err := Record([]info.Proxy{testNode("2.2.2.2", true, 100)}, start.Add(staleAfter)); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := Lookup(fingerprint("1.1.1.1")); len(entry.Samples) != 1 {
+		t.Errorf("node seen exactly %v ago was pruned", staleAfter)
+	}
+	if err := Record([]info.Proxy{testNode("2.2.2.2", true, 100)}, start.Add(staleAfter+time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := Lookup(fingerprint("1.1.1.1")); len(entry.Samples) != 0 {
+		t.Errorf("stale node kept: %+v", entry)
+	}
+	if entry, _ := Lookup(fingerprint("2.2.2.2")); len(entry.Samples) != 3 {
+		t.Errorf("active node samples = %d, want 3", len(entry.Samples))
+	}
+}
+
+func TestReuse(t *testing.T) {
+	openTestDb(t)
+	checked := time.Unix(1700000000, 0)
+	full := testNode("1.1.1.1", true, 100)
+	full.Info.Country = "JP"
+	full.Info.Unlock.Netflix.Set(info.UnlockFull, "jp")
+	dead := testNode("2.2.2.2", false, 0)
+	if err := Record([]info.Proxy{full, dead}, checked); err != nil {
+		t.Fatal(err)
+	}
+	ttl := time.Hour
+
+	node := testNode("1.1.1.1", true, 80)
+	if !Reuse(&node, ttl, checked.Add(30*time.Minute)) {
+		t.Fatal("fresh results not reused")
+	}
+	if !node.Info.Cached || node.Info.Country != "JP" || node.Info.Unlock.Netflix.Level != info.UnlockFull {
+		t.Errorf("reused info = %+v", node.Info)
+	}
+
+	// a run with reused results keeps the time of the full check
+	if err := Record([]info.Proxy{node}, checked.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	expired := testNode("1.1.1.1", true, 80)
+	if Reuse(&expired, ttl, checked.Add(61*time.Minute)) {
+		t.Error("results older than the ttl reused")
+	}
+	deadAgain := testNode("2.2.2.2", true, 80)
+	if Reuse(&deadAgain, ttl, checked.Add(time.Minute)) {
+		t.Error("results of a dead node reused")
+	}
+	if Reuse(&node, 0, checked) {
+		t.Error("reused with the incremental mode off")
+	}
+}
@@ -10,23 +10,25 @@ import (
 )
 
 var (
-	dedupProxies   map[string]*Proxy
+	dedupProxies   map[string]int
 	dedupMutex     sync.Mutex
 	dnsCache       map[string]string
 	dnsCacheMutex  sync.Mutex
 )
 
-func addDedupProxy(key string, p *Proxy) {
+// addDedupProxy keeps the first proxy in list order for every key, the
+// tasks finish in any order.
+func addDedupProxy(key string, index int) {
 	dedupMutex.Lock()
 	defer dedupMutex.Unlock()
-	if _, exists := dedupProxies[key]; !exists {
-		dedupProxies[key] = p
+	if kept, exists := dedupProxies[key]; !exists || index < kept {
+		dedupProxies[key] = index
 	}
 }
 
 func DeduplicateProxies(proxies *[]Proxy) {
 	var wg sync.WaitGroup
-	dedupProxies = make(map[string]*Proxy)
+	dedupProxies = make(map[string]int)
 	dnsCache = make(map[string]string)
 
 	pool, _ := ants.NewPool(config.GlobalConfig.Check.Concurrent)
@@ -37,15 +39,22 @@ func DeduplicateProxies(proxies *[]Proxy) {
 		i := i
 		pool.Submit(func() {
 			defer wg.Done()
-			deduplicateTask(&(*proxies)[i])
+			deduplicateTask(&(*proxies)[i], i)
 		})
 	}
 	wg.Wait()
 
-	*proxies = (*proxies)[:0]
-	for _, proxy := range dedupProxies {
-		*proxies = append(*proxies, *proxy)
+	kept := make([]bool, len(*proxies))
+	for _, index := range dedupProxies {
+		kept[index] = true
 	}
+	unique := (*proxies)[:0]
+	for i, proxy := range *proxies {
+		if kept[i] {
+			unique = append(unique, proxy)
+		}
+	}
+	*proxies = unique
 
 	dedupProxies = nil
 	dnsCache = nil
@@ -71,7 +80,7 @@ func resolveServerKey(server string) string {
 	return resolved
 }
 
-func deduplicateTask(p *Proxy) {
+func deduplicateTask(p *Proxy, index int) {
 	arg := p.Raw
 	server, serverOk := "", false
 	if arg["type"] == "vless" || arg["type"] == "vmess" {
@@ -89,5 +98,27 @@ func deduplicateTask(p *Proxy) {
 	}
 
 	key := fmt.Sprintf("%s:%v", resolveServerKey(server), port)
-	addDedupProxy(key, p)
+	addDedupProxy(key, index)
+}
+
+// DeduplicateByEgress keeps the lowest delay node of every exit address, nodes
+// whose exit address is unknown are all kept.
+func DeduplicateByEgress(proxies *[]Proxy) {
+	best := make(map[string]int)
+	for i, p := range *proxies {
+		if p.Info.IP == "" {
+			continue
+		}
+		if j, ok := best[p.Info.IP]; !ok || p.Info.Delay < (*proxies)[j].Info.Delay {
+			best[p.Info.IP] = i
+		}
+	}
+
+	result := make([]Proxy, 0, len(*proxies))
+	for i, p := range *proxies {
+		if p.Info.IP == "" || best[p.Info.IP] == i {
+			result = append(result, p)
+		}
+	}
+	*proxies = result
 }
@@ -0,0 +1,39 @@
+package info
+
+import (
+	"testing"
+
+	"github.com/bestruirui/bestsub/config"
+)
+
+func TestDeduplicateProxiesKeepsFirst(t *testing.T) {
+	saved := config.GlobalConfig.Check.Concurrent
+	t.Cleanup(func() { config.GlobalConfig.Check.Concurrent = saved })
+	config.GlobalConfig.Check.Concurrent = 8
+
+	node := func(server string, port int, subUrl string) Proxy {
+		return Proxy{Raw: map[string]any{"type": "ss", "server": server, "port": port}, SubUrl: subUrl}
+	}
+	for run := 0; run < 50; run++ {
+		proxies := []Proxy{
+			node("1.1.1.1", 443, "a"),
+			node("2.2.2.2", 443, "a"),
+			node("2.2.2.2", 443, "b"),
+			node("1.1.1.1", 443, "b"),
+			node("3.3.3.3", 443, "b"),
+			node("3.3.3.3", 8443, "c"),
+			node("3.3.3.3", 443, "c"),
+		}
+		DeduplicateProxies(&proxies)
+
+		want := []string{"1.1.1.1:a", "2.2.2.2:a", "3.3.3.3:b", "3.3.3.3:c"}
+		if len(proxies) != len(want) {
+			t.Fatalf("run %d: got %d proxies, want %d", run, len(proxies), len(want))
+		}
+		for i, proxy := range proxies {
+			if got := proxy.Raw["server"].(string) + ":" + proxy.SubUrl; got != want[i] {
+				t.Fatalf("run %d: proxy %d = %s, want %s", run, i, got, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package info
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Fingerprint returns a stable identifier for the node. The name is ignored so
+// that a node keeps its identity when the subscription or the rename step
+// changes it.
+func (p *Proxy) Fingerprint() string {
+	fields := make(map[string]any, len(p.Raw))
+	for key, value := range p.Raw {
+		if key == "name" {
+			continue
+		}
+		fields[key] = value
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		data = []byte(fmt.Sprint(fields))
+	}
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
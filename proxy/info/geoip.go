@@ -0,0 +1,122 @@
+package info
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/spf13/cast"
+)
+
+var (
+	geoipDb     *maxminddb.Reader
+	geoipDbLock sync.RWMutex
+)
+
+// GeoipInit opens a local country database, both MaxMind GeoLite2-Country
+// and mihomo geoip.metadb files are accepted.
+func GeoipInit(path string) error {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return fmt.Errorf("open geoip db failed: %w", err)
+	}
+	geoipDbLock.Lock()
+	defer geoipDbLock.Unlock()
+	if geoipDb != nil {
+		geoipDb.Close()
+	}
+	geoipDb = db
+	return nil
+}
+
+func GeoipClose() {
+	geoipDbLock.Lock()
+	defer geoipDbLock.Unlock()
+	if geoipDb != nil {
+		geoipDb.Close()
+		geoipDb = nil
+	}
+}
+
+// GeoipLookup returns the country code of an address, empty when unknown.
+func GeoipLookup(ip net.IP) string {
+	geoipDbLock.RLock()
+	defer geoipDbLock.RUnlock()
+	if geoipDb == nil || ip == nil {
+		return ""
+	}
+
+	switch geoipDb.Metadata.DatabaseType {
+	case "sing-geoip":
+		var code string
+		if err := geoipDb.Lookup(ip, &code); err != nil {
+			return ""
+		}
+		return countryCode(code)
+	case "Meta-geoip0":
+		// 记录可能是单个代码或代码列表，列表中还会有 private 等非国家代码
+		var record any
+		if err := geoipDb.Lookup(ip, &record); err != nil {
+			return ""
+		}
+		if code, ok := record.(string); ok {
+			return countryCode(code)
+		}
+		for _, code := range cast.ToStringSlice(record) {
+			if code := countryCode(code); code != "" {
+				return code
+			}
+		}
+		return ""
+	default:
+		var record struct {
+			Country struct {
+				IsoCode string `maxminddb:"iso_code"`
+			} `maxminddb:"country"`
+		}
+		if err := geoipDb.Lookup(ip, &record); err != nil {
+			return ""
+		}
+		return countryCode(record.Country.IsoCode)
+	}
+}
+
+func countryCode(code string) string {
+	if len(code) != 2 {
+		return ""
+	}
+	return strings.ToUpper(code)
+}
+
+// CountryCodeFromGeoip resolves the country of the exit address, or of the
+// server address when the exit address is unknown, in the local database.
+func (p *Proxy) CountryCodeFromGeoip() {
+	code := GeoipLookup(net.ParseIP(p.Info.IP))
+	if code == "" {
+		code = GeoipLookup(p.serverIP())
+	}
+	if code == "" {
+		p.Info.Country = "UN"
+	} else {
+		p.Info.Country = code
+	}
+}
+
+func (p *Proxy) serverIP() net.IP {
+	server := cast.ToString(p.Raw["server"])
+	if ip := net.ParseIP(server); ip != nil {
+		return ip
+	}
+	ctx := context.Background()
+	if p.Ctx != nil {
+		ctx = p.Ctx
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", server)
+	if err != nil || len(ips) == 0 {
+		return nil
+	}
+	return ips[0]
+}
@@ -0,0 +1,93 @@
+package info
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// writeGeoipDb writes a fixture country database of the given type and
+// opens it with GeoipInit.
+func writeGeoipDb(t *testing.T, databaseType string, networks map[string]mmdbtype.DataType) {
+	t.Helper()
+	writer, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: databaseType, RecordSize: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cidr, record := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Insert(network, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "geoip.mmdb")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.WriteTo(file); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := GeoipInit(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(GeoipClose)
+}
+
+func TestGeoipLookup(t *testing.T) {
+	tests := []struct {
+		databaseType string
+		networks     map[string]mmdbtype.DataType
+		want         map[string]string
+	}{
+		{
+			databaseType: "GeoLite2-Country",
+			networks: map[string]mmdbtype.DataType{
+				"1.0.0.0/24": mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String("JP")}},
+				"1.0.1.0/24": mmdbtype.Map{"continent": mmdbtype.Map{"code": mmdbtype.String("EU")}},
+			},
+			want: map[string]string{"1.0.0.1": "JP", "1.0.1.1": "", "8.8.8.8": ""},
+		},
+		{
+			databaseType: "sing-geoip",
+			networks: map[string]mmdbtype.DataType{
+				"1.0.0.0/24": mmdbtype.String("us"),
+				"1.0.1.0/24": mmdbtype.String("private"),
+			},
+			want: map[string]string{"1.0.0.1": "US", "1.0.1.1": "", "8.8.8.8": ""},
+		},
+		{
+			databaseType: "Meta-geoip0",
+			networks: map[string]mmdbtype.DataType{
+				"1.0.0.0/24": mmdbtype.String("hk"),
+				"1.0.1.0/24": mmdbtype.Slice{mmdbtype.String("private"), mmdbtype.String("sg")},
+				"1.0.2.0/24": mmdbtype.Slice{mmdbtype.String("private")},
+			},
+			want: map[string]string{"1.0.0.1": "HK", "1.0.1.1": "SG", "1.0.2.1": "", "8.8.8.8": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.databaseType, func(t *testing.T) {
+			writeGeoipDb(t, tt.databaseType, tt.networks)
+			for ip, want := range tt.want {
+				if got := GeoipLookup(net.ParseIP(ip)); got != want {
+					t.Errorf("GeoipLookup(%s) = %q, want %q", ip, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGeoipLookupClosed(t *testing.T) {
+	if got := GeoipLookup(net.ParseIP("1.0.0.1")); got != "" {
+		t.Errorf("GeoipLookup without a database = %q", got)
+	}
+}
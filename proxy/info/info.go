@@ -13,34 +13,49 @@ import (
 )
 
 type Unlock struct {
-	Google     bool
-	Chatgpt    bool
-	Netflix    bool
-	Disney     bool
-	Youtube    bool
-	Cloudflare bool
+	Google     bool         `json:"google"`
+	Chatgpt    UnlockResult `json:"chatgpt"`
+	Netflix    UnlockResult `json:"netflix"`
+	Disney     UnlockResult `json:"disney"`
+	Youtube    UnlockResult `json:"youtube"`
+	Cloudflare bool         `json:"cloudflare"`
 }
 
 type ProxyInfo struct {
-	Unlock    Unlock
-	Speed     int
-	SpeedSkip bool
-	Rate      float32
-	Risk      int
-	Delay     uint16
-	Alive     bool
-	Country   string
-	Flag      string
+	Unlock    Unlock  `json:"unlock"`
+	Speed     int     `json:"speed"`
+	SpeedSkip bool    `json:"speed-skip"`
+	Rate      float32 `json:"rate"`
+	Risk      int     `json:"risk"`
+	Delay     uint16  `json:"delay"`
+	Alive     bool    `json:"alive"`
+	Country   string  `json:"country"`
+	Flag      string  `json:"flag"`
+	Uptime    float32 `json:"uptime"`
+	Jitter    uint16  `json:"jitter"`
+	// Checks holds the result of every check plugin by name
+	Checks map[string]bool `json:"checks"`
+	// Tags holds the tags of the passed probes
+	Tags []string `json:"tags"`
+	// IP is the exit address of the node, IPType, Asn and AsOrg describe it
+	IP     string `json:"ip"`
+	IPType string `json:"ip-type"`
+	Asn    uint   `json:"asn"`
+	AsOrg  string `json:"as-org"`
+	// Cached is set when the results were reused from history
+	Cached bool `json:"cached"`
 }
 
 type Proxy struct {
 	Raw    map[string]any
 	Id     int
 	SubUrl string
-	Ctx    context.Context
-	Cancel context.CancelFunc
-	Client *http.Client
-	Info   ProxyInfo
+	// SubAlias is the alias of the subscription the node comes from
+	SubAlias string
+	Ctx      context.Context
+	Cancel   context.CancelFunc
+	Client   *http.Client
+	Info     ProxyInfo
 }
 
 func (p *Proxy) Close() {
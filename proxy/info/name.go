@@ -0,0 +1,113 @@
+package info
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cast"
+)
+
+// NameData is the data a rename.template is executed with.
+type NameData struct {
+	ProxyInfo
+	// Name is the name the node had in its subscription
+	Name string
+	Type string
+	Sub  string
+	// Alias is the alias of the subscription, empty when not set
+	Alias string
+	// Index counts all nodes and CountryIndex the nodes of the same country,
+	// both start from 1
+	Index        int
+	CountryIndex int
+	// Unlocks lists the unlocked services like NF-JP
+	Unlocks []string
+	// SpeedText is the speed formatted like 1.25 MB/s, empty when untested
+	SpeedText string
+}
+
+var nameFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseNameTemplate parses a rename.template and executes it once with empty
+// data so that unknown fields are reported before the first run.
+func ParseNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name").Funcs(nameFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse name template failed: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, NameData{}); err != nil {
+		return nil, fmt.Errorf("execute name template failed: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RenderNames renames the nodes in order with a rename.template. The names are
+// only applied when the template executes for every node, so a failure leaves
+// all nodes with their previous names.
+func RenderNames(text string, proxies []Proxy) error {
+	tmpl, err := ParseNameTemplate(text)
+	if err != nil {
+		return err
+	}
+	counters := make(map[string]int)
+	used := make(map[string]int)
+	names := make([]string, len(proxies))
+	var buf bytes.Buffer
+	for i := range proxies {
+		p := &proxies[i]
+		p.CountryFlag()
+		p.ParseRate()
+		counters[p.Info.Country]++
+
+		data := NameData{
+			ProxyInfo:    p.Info,
+			Name:         cast.ToString(p.Raw["name"]),
+			Type:         cast.ToString(p.Raw["type"]),
+			Sub:          p.SubUrl,
+			Alias:        p.SubAlias,
+			Index:        i + 1,
+			CountryIndex: counters[p.Info.Country],
+			Unlocks:      p.Info.Unlock.Labels(),
+		}
+		if p.Info.Speed > 0 {
+			data.SpeedText = SpeedText(p.Info.Speed)
+		}
+
+		buf.Reset()
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("execute name template for %s failed: %w", data.Name, err)
+		}
+		name := strings.TrimSpace(buf.String())
+		if name == "" {
+			name = data.Name
+		}
+		// 节点名称必须唯一，重复的名称加上序号
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s %d", name, used[name])
+		}
+		names[i] = name
+	}
+	for i := range proxies {
+		proxies[i].Raw["name"] = names[i]
+	}
+	return nil
+}
+
+// SpeedText formats a speed in KB/s.
+func SpeedText(speed int) string {
+	switch {
+	case speed < 1024:
+		return fmt.Sprintf("%d KB/s", speed)
+	case speed < 1024*1024:
+		return fmt.Sprintf("%.2f MB/s", float64(speed)/1024)
+	default:
+		return fmt.Sprintf("%.2f GB/s", float64(speed)/(1024*1024))
+	}
+}
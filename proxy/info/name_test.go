@@ -0,0 +1,46 @@
+package info
+
+import (
+	"reflect"
+	"testing"
+)
+
+func nameTestProxies() []Proxy {
+	return []Proxy{
+		{Raw: map[string]any{"name": "a", "type": "ss"}, Info: ProxyInfo{Country: "JP"}},
+		{Raw: map[string]any{"name": "b", "type": "ss"}, Info: ProxyInfo{Country: "JP", Tags: []string{"stable"}}},
+		{Raw: map[string]any{"name": "c", "type": "ss"}, Info: ProxyInfo{Country: "US"}},
+	}
+}
+
+func proxyNames(proxies []Proxy) []any {
+	names := make([]any, 0, len(proxies))
+	for _, p := range proxies {
+		names = append(names, p.Raw["name"])
+	}
+	return names
+}
+
+func TestRenderNames(t *testing.T) {
+	proxies := nameTestProxies()
+	if err := RenderNames(`{{.Country}}`, proxies); err != nil {
+		t.Fatal(err)
+	}
+	want := []any{"JP", "JP 2", "US"}
+	if got := proxyNames(proxies); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
+
+func TestRenderNamesFailureKeepsNames(t *testing.T) {
+	proxies := nameTestProxies()
+	// the template passes the startup check with empty data but fails on the
+	// second node
+	if err := RenderNames(`{{.Country}}{{if .Tags}}{{index .Tags 5}}{{end}}`, proxies); err == nil {
+		t.Fatal("expected error")
+	}
+	want := []any{"a", "b", "c"}
+	if got := proxyNames(proxies); !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package info
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Unlock levels of a streaming or AI service, an unchecked service has an
+// empty level.
+const (
+	UnlockFull      = "full"
+	UnlockOriginals = "originals"
+	UnlockBlocked   = "blocked"
+)
+
+// UnlockResult is the result of an unlock check with the region reported by
+// the service.
+type UnlockResult struct {
+	Level  string `json:"level"`
+	Region string `json:"region"`
+}
+
+// Unlocked reports a full unlock, an originals-only result is only visible
+// through Level.
+func (r UnlockResult) Unlocked() bool {
+	return r.Level == UnlockFull
+}
+
+func (r *UnlockResult) Set(level, region string) {
+	r.Level = level
+	r.Region = strings.ToUpper(region)
+}
+
+// UnmarshalJSON also accepts the plain booleans written by older versions.
+func (r *UnlockResult) UnmarshalJSON(data []byte) error {
+	var unlocked bool
+	if err := json.Unmarshal(data, &unlocked); err == nil {
+		*r = UnlockResult{}
+		if unlocked {
+			r.Level = UnlockFull
+		}
+		return nil
+	}
+	type plain UnlockResult
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// Labels lists the unlocked services with their region, such as NF-JP, for
+// use in node names.
+func (u Unlock) Labels() []string {
+	services := []struct {
+		label  string
+		result UnlockResult
+	}{
+		{"GPT", u.Chatgpt},
+		{"NF", u.Netflix},
+		{"D+", u.Disney},
+		{"YT", u.Youtube},
+	}
+	var labels []string
+	for _, service := range services {
+		if !service.result.Unlocked() && service.result.Level != UnlockOriginals {
+			continue
+		}
+		label := service.label
+		if service.result.Level == UnlockOriginals {
+			label += "(O)"
+		}
+		if service.result.Region != "" {
+			label += "-" + service.result.Region
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
@@ -0,0 +1,42 @@
+package info
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUnlocked(t *testing.T) {
+	tests := map[string]bool{
+		UnlockFull:      true,
+		UnlockOriginals: false,
+		UnlockBlocked:   false,
+		"":              false,
+	}
+	for level, want := range tests {
+		if got := (UnlockResult{Level: level}).Unlocked(); got != want {
+			t.Errorf("Unlocked(%q) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+func TestUnlockLabels(t *testing.T) {
+	var u Unlock
+	u.Netflix.Set(UnlockOriginals, "jp")
+	u.Youtube.Set(UnlockFull, "us")
+	u.Disney.Set(UnlockBlocked, "us")
+	want := []string{"NF(O)-JP", "YT-US"}
+	if got := u.Labels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels = %v, want %v", got, want)
+	}
+}
+
+func TestUnlockResultLegacyJSON(t *testing.T) {
+	var u Unlock
+	if err := json.Unmarshal([]byte(`{"netflix":true,"disney":false,"youtube":{"level":"originals","region":"JP"}}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	if !u.Netflix.Unlocked() || u.Disney.Level != "" || u.Youtube.Level != UnlockOriginals {
+		t.Errorf("unmarshal = %+v", u)
+	}
+}
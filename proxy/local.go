@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/utils/log"
+	"github.com/fsnotify/fsnotify"
+)
+
+const filePrefix = "file://"
+
+var (
+	localWatcher     *fsnotify.Watcher
+	localWatcherLock sync.Mutex
+	// localDirs are the directories watched for the local sources
+	localDirs = make(map[string]bool)
+)
+
+func isLocalSub(subUrl string) bool {
+	return strings.HasPrefix(strings.ToLower(subUrl), filePrefix)
+}
+
+// localPattern turns a file:// url into a path or glob pattern.
+func localPattern(subUrl string) string {
+	path := subUrl[len(filePrefix):]
+	// file:///C:/subs/*.yaml
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// readLocalSub reads every file matching a file:// url.
+func readLocalSub(subUrl string) ([][]byte, error) {
+	pattern := localPattern(subUrl)
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file matches %s", pattern)
+	}
+
+	contents := make([][]byte, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Warn("read local subscription file %s failed: %v", file, err)
+			continue
+		}
+		log.Debug("read local subscription file %s", file)
+		contents = append(contents, data)
+	}
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no readable file matches %s", pattern)
+	}
+	return contents, nil
+}
+
+// WatchLocalSources watches the directories of the file:// subscriptions,
+// it is called again after the config is reloaded to follow the new list.
+func WatchLocalSources() error {
+	localWatcherLock.Lock()
+	defer localWatcherLock.Unlock()
+
+	dirs := make(map[string]bool)
+	for _, sub := range config.GlobalConfig.SubUrls {
+		if isLocalSub(sub.Url) {
+			dirs[filepath.Dir(localPattern(sub.Url))] = true
+		}
+	}
+	if len(dirs) == 0 && localWatcher == nil {
+		return nil
+	}
+
+	if localWatcher == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("create local source watcher failed: %w", err)
+		}
+		localWatcher = watcher
+		go watchLocalEvents(watcher)
+	}
+
+	for dir := range localDirs {
+		if !dirs[dir] {
+			localWatcher.Remove(dir)
+			delete(localDirs, dir)
+		}
+	}
+	for dir := range dirs {
+		if localDirs[dir] {
+			continue
+		}
+		if err := localWatcher.Add(dir); err != nil {
+			log.Warn("watch local subscription directory %s failed: %v", dir, err)
+			continue
+		}
+		localDirs[dir] = true
+		log.Info("watching local subscription directory: %v", dir)
+	}
+	return nil
+}
+
+// CloseLocalWatcher stops watching the local sources.
+func CloseLocalWatcher() {
+	localWatcherLock.Lock()
+	defer localWatcherLock.Unlock()
+	if localWatcher != nil {
+		localWatcher.Close()
+		localWatcher = nil
+		localDirs = make(map[string]bool)
+	}
+}
+
+func watchLocalEvents(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			for _, sub := range config.GlobalConfig.SubUrls {
+				if !isLocalSub(sub.Url) {
+					continue
+				}
+				if matched, _ := filepath.Match(localPattern(sub.Url), event.Name); matched {
+					log.Info("local subscription file %s changed, picked up in the next run", event.Name)
+					markSourceChanged(sub.Url)
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Error("local subscription watcher error: %v", err)
+		}
+	}
+}
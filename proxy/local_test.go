@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bestruirui/bestsub/config"
+)
+
+func TestReadLocalSub(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"a.yaml": "a", "b.yaml": "b", "c.txt": "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contents, err := readLocalSub("file://" + filepath.ToSlash(filepath.Join(dir, "*.yaml")))
+	if err != nil || len(contents) != 2 {
+		t.Fatalf("readLocalSub = %d files, %v", len(contents), err)
+	}
+	if _, err := readLocalSub("file://" + filepath.ToSlash(filepath.Join(dir, "*.json"))); err == nil {
+		t.Error("expected an error when no file matches")
+	}
+}
+
+func TestLocalChangeMarksSource(t *testing.T) {
+	dir := t.TempDir()
+	subUrl := "file://" + filepath.ToSlash(filepath.Join(dir, "*.yaml"))
+	saved := config.GlobalConfig.SubUrls
+	config.GlobalConfig.SubUrls = []config.SubConfig{{Url: subUrl}}
+	t.Cleanup(func() {
+		CloseLocalWatcher()
+		config.GlobalConfig.SubUrls = saved
+	})
+	if err := WatchLocalSources(); err != nil {
+		t.Fatal(err)
+	}
+	changed := func() bool {
+		sourceStatsLock.Lock()
+		defer sourceStatsLock.Unlock()
+		return getSourceStat(subUrl).changed
+	}
+
+	// a file the pattern does not match is ignored
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if changed() {
+		t.Fatal("marked by a file outside the pattern")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "sub.yaml"), []byte("proxies: []"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); !changed(); time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("changed source is not marked for a muted re-read")
+		}
+	}
+}
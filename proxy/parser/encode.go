@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// EncodeProxy converts a mihomo proxy map back to a share link.
+func EncodeProxy(proxy map[string]any) (string, error) {
+	switch cast.ToString(proxy["type"]) {
+	case "ss":
+		return EncodeShadowsocks(proxy)
+	case "ssr":
+		return EncodeSsr(proxy)
+	case "vmess":
+		return EncodeVmess(proxy)
+	case "vless":
+		return EncodeVless(proxy)
+	case "trojan":
+		return EncodeTrojan(proxy)
+	case "hysteria2":
+		return EncodeHysteria2(proxy)
+	case "hysteria":
+		return EncodeHysteria(proxy)
+	case "tuic":
+		return EncodeTuic(proxy)
+	case "wireguard":
+		return EncodeWireguard(proxy)
+	case "socks5":
+		return EncodeSocks(proxy)
+	case "http":
+		return EncodeHttp(proxy)
+	}
+	return "", fmt.Errorf("unsupported proxy type: %v", proxy["type"])
+}
+
+func hostPort(proxy map[string]any) string {
+	return net.JoinHostPort(cast.ToString(proxy["server"]), strconv.Itoa(cast.ToInt(proxy["port"])))
+}
+
+// escapeName escapes a proxy name for the fragment of a share link. Spaces
+// become %20 so that both query and fragment unescaping restore them.
+func escapeName(name any) string {
+	return strings.ReplaceAll(url.QueryEscape(cast.ToString(name)), "+", "%20")
+}
+
+func buildLink(scheme string, user *url.Userinfo, proxy map[string]any, query url.Values) string {
+	link := url.URL{
+		Scheme:   scheme,
+		User:     user,
+		Host:     hostPort(proxy),
+		RawQuery: query.Encode(),
+	}
+	return link.String() + "#" + escapeName(proxy["name"])
+}
+
+func setQuery(query url.Values, key string, value any) {
+	if s := cast.ToString(value); s != "" {
+		query.Set(key, s)
+	}
+}
+
+func wsHostPath(proxy map[string]any) (string, string) {
+	opts := cast.ToStringMap(proxy["ws-opts"])
+	return cast.ToString(cast.ToStringMap(opts["headers"])["Host"]), cast.ToString(opts["path"])
+}
+
+func grpcServiceName(proxy map[string]any) string {
+	return cast.ToString(cast.ToStringMap(proxy["grpc-opts"])["grpc-service-name"])
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// ParseHysteria parses hysteria v1 links such as
+// hysteria://host:port?protocol=udp&auth=xxx&peer=sni&upmbps=100&downmbps=100&obfsParam=xxx#name
+func ParseHysteria(data string) (map[string]any, error) {
+	u, port, err := parseLink(data, "hysteria")
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+
+	proxy := map[string]any{
+		"name":   u.Fragment,
+		"type":   "hysteria",
+		"server": u.Hostname(),
+		"port":   port,
+	}
+	setNonEmpty(proxy, "protocol", query.Get("protocol"))
+	setNonEmpty(proxy, "auth-str", query.Get("auth"))
+	setNonEmpty(proxy, "sni", query.Get("peer"))
+	setNonEmpty(proxy, "up", query.Get("upmbps"))
+	setNonEmpty(proxy, "down", query.Get("downmbps"))
+	// obfs is the xplus obfuscation password in mihomo
+	setNonEmpty(proxy, "obfs", query.Get("obfsParam"))
+	setNonEmpty(proxy, "ports", query.Get("mport"))
+	if alpn := splitList(query.Get("alpn")); len(alpn) > 0 {
+		proxy["alpn"] = alpn
+	}
+	if queryBool(query, "insecure") {
+		proxy["skip-cert-verify"] = true
+	}
+	return proxy, nil
+}
+
+func EncodeHysteria(proxy map[string]any) (string, error) {
+	query := url.Values{}
+	setQuery(query, "protocol", proxy["protocol"])
+	setQuery(query, "auth", proxy["auth-str"])
+	setQuery(query, "peer", proxy["sni"])
+	setQuery(query, "upmbps", bandwidthMbps(proxy["up"]))
+	setQuery(query, "downmbps", bandwidthMbps(proxy["down"]))
+	if obfs := cast.ToString(proxy["obfs"]); obfs != "" {
+		query.Set("obfs", "xplus")
+		query.Set("obfsParam", obfs)
+	}
+	setQuery(query, "mport", proxy["ports"])
+	setQuery(query, "alpn", strings.Join(cast.ToStringSlice(proxy["alpn"]), ","))
+	if cast.ToBool(proxy["skip-cert-verify"]) {
+		query.Set("insecure", "1")
+	}
+	return buildLink("hysteria", nil, proxy, query), nil
+}
+
+// bandwidthMbps strips the unit of mihomo bandwidth values such as "100 Mbps".
+func bandwidthMbps(value any) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.ToLower(cast.ToString(value)), "mbps"))
+}
@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/spf13/cast"
 )
 
 func ParseHysteria2(data string) (map[string]any, error) {
@@ -30,7 +32,7 @@ func ParseHysteria2(data string) (map[string]any, error) {
 	if err != nil {
 		return nil, fmt.Errorf("hysteria2 port error")
 	}
-	_, obfs, obfsPassword, _, insecure, sni := query.Get("network"), query.Get("obfs"), query.Get("obfs-password"), query.Get("pinSHA256"), query.Get("insecure"), query.Get("sni")
+	_, obfs, obfsPassword, pinSHA256, insecure, sni := query.Get("network"), query.Get("obfs"), query.Get("obfs-password"), query.Get("pinSHA256"), query.Get("insecure"), query.Get("sni")
 	insecureBool := insecure == "1"
 
 	return map[string]any{
@@ -39,12 +41,26 @@ func ParseHysteria2(data string) (map[string]any, error) {
 		"server":           server,
 		"port":             port,
 		"ports":            query.Get("mport"),
-		"password":         link.User.String(),
+		"password":         userPassword(link.User),
 		"obfs":             obfs,
 		"obfs-password":    obfsPassword,
 		"sni":              sni,
 		"skip-cert-verify": insecureBool,
 		"insecure":         insecure,
 		"mport":            query.Get("mport"),
+		"fingerprint":      pinSHA256,
 	}, nil
 }
+
+func EncodeHysteria2(proxy map[string]any) (string, error) {
+	query := url.Values{}
+	setQuery(query, "sni", proxy["sni"])
+	setQuery(query, "obfs", proxy["obfs"])
+	setQuery(query, "obfs-password", proxy["obfs-password"])
+	setQuery(query, "mport", proxy["ports"])
+	setQuery(query, "pinSHA256", proxy["fingerprint"])
+	if cast.ToBool(proxy["skip-cert-verify"]) {
+		query.Set("insecure", "1")
+	}
+	return buildLink("hysteria2", url.User(cast.ToString(proxy["password"])), proxy, query), nil
+}
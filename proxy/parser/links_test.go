@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/metacubex/mihomo/adapter"
+)
+
+const (
+	testUUID   = "b831381d-6324-4d53-ad4f-8cda48b30811"
+	testWgPriv = "eCtXsJZ27+4PbhDkHnB923tkUn2Gj59wZw5wFA75MnU="
+	testWgPub  = "Cr8hWlKvtDt7nrvf+f0brNQQzabAqrjfBvas9pmowjo="
+)
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		// want lists fields of the parsed proxy, nil when the link is rejected
+		want map[string]any
+	}{
+		{"tuic", "tuic://" + testUUID + ":pw@1.1.1.1:443?sni=a.com&congestion_control=bbr&udp_relay_mode=quic&alpn=h3,h3-29&allow_insecure=1#tuic",
+			map[string]any{"type": "tuic", "uuid": testUUID, "password": "pw", "sni": "a.com", "congestion-controller": "bbr",
+				"udp-relay-mode": "quic", "alpn": []string{"h3", "h3-29"}, "skip-cert-verify": true}},
+		{"tuic disable sni", "tuic://" + testUUID + ":pw@1.1.1.1:443?disable_sni=1#t", map[string]any{"disable-sni": true}},
+		{"tuic no port", "tuic://" + testUUID + ":pw@1.1.1.1#t", nil},
+		{"hysteria", "hysteria://1.1.1.2:443?protocol=udp&auth=secret&peer=a.com&upmbps=50&downmbps=100&obfsParam=ob&insecure=1&alpn=h3#hy",
+			map[string]any{"type": "hysteria", "auth-str": "secret", "sni": "a.com", "up": "50", "down": "100", "obfs": "ob", "skip-cert-verify": true}},
+		{"wireguard", "wireguard://" + testWgPriv + "@1.1.1.3:51820?publickey=" + testWgPub + "&address=10.0.0.2/32,fd00::2/128&reserved=1,2,3&mtu=1280#wg",
+			map[string]any{"type": "wireguard", "private-key": testWgPriv, "public-key": testWgPub, "ip": "10.0.0.2", "ipv6": "fd00::2",
+				"reserved": []int{1, 2, 3}, "mtu": 1280}},
+		{"wg alias", "wg://" + testWgPriv + "@1.1.1.3:51820?publicKey=" + testWgPub + "&ip=10.0.0.3#wg", map[string]any{"ip": "10.0.0.3"}},
+		{"wireguard without public key", "wireguard://" + testWgPriv + "@1.1.1.3:51820#wg", nil},
+		{"socks5", "socks5://user:pw@1.1.1.4:1080#s", map[string]any{"type": "socks5", "username": "user", "password": "pw", "udp": true}},
+		{"socks base64 user", "socks://dXNlcjpwdw==@1.1.1.4:1080#s", map[string]any{"username": "user", "password": "pw"}},
+		{"socks tls", "socks5://1.1.1.4:1080?tls=1&allowInsecure=1#s", map[string]any{"tls": true, "skip-cert-verify": true}},
+		{"http", "http://user:pw@1.1.1.5:8080#h", map[string]any{"type": "http", "username": "user", "password": "pw"}},
+		{"https", "https://1.1.1.5:8443?sni=a.com&insecure=1#h", map[string]any{"type": "http", "tls": true, "sni": "a.com", "skip-cert-verify": true}},
+		{"http web page", "https://example.com:443/index.html", nil},
+		{"http without port", "https://example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := ParseProxy(tt.link)
+			if tt.want == nil {
+				if err == nil && proxy != nil {
+					t.Fatalf("link accepted: %v", proxy)
+				}
+				return
+			}
+			if err != nil || proxy == nil {
+				t.Fatalf("parse: %v", err)
+			}
+			for key, value := range tt.want {
+				if got := fmt.Sprint(proxy[key]); got != fmt.Sprint(value) {
+					t.Errorf("%s = %v, want %v", key, got, value)
+				}
+			}
+			// wireguard needs the with_gvisor build tag
+			if proxy["type"] == "wireguard" {
+				return
+			}
+			if _, err := adapter.ParseProxy(proxy); err != nil {
+				t.Errorf("adapter: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnregisteredScheme(t *testing.T) {
+	if IsProxyLink([]byte("anytls://pw@1.1.1.1:443#a")) {
+		t.Error("anytls links are detected although mihomo cannot check them")
+	}
+	if proxy, err := ParseProxy("anytls://pw@1.1.1.1:443#a"); proxy != nil || err != nil {
+		t.Errorf("ParseProxy = %v, %v", proxy, err)
+	}
+}
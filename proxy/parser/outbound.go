@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/spf13/cast"
+)
+
+// outbounds reads the outbound objects of a sing-box or Xray config, a bare
+// list of outbounds is accepted too.
+func outbounds(data []byte) ([]map[string]any, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return nil, false
+	}
+	var list []map[string]any
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, false
+		}
+	} else {
+		var config struct {
+			Outbounds []map[string]any `json:"outbounds"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil || config.Outbounds == nil {
+			return nil, false
+		}
+		list = config.Outbounds
+	}
+	for _, outbound := range list {
+		if _, ok := outbound["type"]; ok {
+			return list, true
+		}
+		if _, ok := outbound["protocol"]; ok {
+			return list, true
+		}
+	}
+	return nil, false
+}
+
+// IsOutbounds reports whether data is a sing-box or Xray config with outbounds.
+func IsOutbounds(data []byte) bool {
+	_, ok := outbounds(data)
+	return ok
+}
+
+// ParseOutbounds converts the outbounds of a sing-box or Xray config to mihomo
+// proxies. Outbounds that are not proxies, such as selectors and direct, are
+// skipped, the errors of unsupported ones are returned with the proxies.
+func ParseOutbounds(data []byte) ([]map[string]any, []error) {
+	list, ok := outbounds(data)
+	if !ok {
+		return nil, []error{fmt.Errorf("not a sing-box or xray config")}
+	}
+
+	proxies := make([]map[string]any, 0, len(list))
+	errs := make([]error, 0)
+	for i, outbound := range list {
+		var converted []map[string]any
+		var err error
+		if _, ok := outbound["protocol"]; ok {
+			converted, err = fromXray(outbound)
+		} else {
+			var proxy map[string]any
+			proxy, err = fromSingbox(outbound)
+			if proxy != nil {
+				converted = []map[string]any{proxy}
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("outbound %d %q: %w", i+1, outboundTag(outbound), err))
+			continue
+		}
+		proxies = append(proxies, converted...)
+	}
+	return proxies, errs
+}
+
+func outboundTag(outbound map[string]any) string {
+	return cast.ToString(outbound["tag"])
+}
+
+// proxyName falls back to the server address for outbounds without a tag.
+func proxyName(tag string, server string, port int) string {
+	if tag != "" {
+		return tag
+	}
+	return net.JoinHostPort(server, strconv.Itoa(port))
+}
+
+func setNonEmpty(values map[string]any, key string, value any) {
+	if s := cast.ToString(value); s != "" {
+		values[key] = s
+	}
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/metacubex/mihomo/adapter"
+)
+
+const singboxConfig = `{
+  "log": {"level": "info"},
+  "outbounds": [
+    {"type": "selector", "tag": "select", "outbounds": ["ss"]},
+    {"type": "direct", "tag": "direct"},
+    {"type": "shadowsocks", "tag": "ss", "server": "1.1.1.1", "server_port": 8388, "method": "aes-128-gcm", "password": "pw",
+     "plugin": "obfs-local", "plugin_opts": "obfs=http;obfs-host=bing.com"},
+    {"type": "vmess", "tag": "vmess-ws", "server": "1.1.1.2", "server_port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811",
+     "tls": {"enabled": true, "server_name": "a.com", "utls": {"enabled": true, "fingerprint": "chrome"}},
+     "transport": {"type": "ws", "path": "/ws", "headers": {"Host": "a.com"}, "max_early_data": 2048, "early_data_header_name": "Sec-WebSocket-Protocol"}},
+    {"type": "vless", "tag": "vless-reality", "server": "1.1.1.3", "server_port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "flow": "xtls-rprx-vision",
+     "tls": {"enabled": true, "server_name": "www.apple.com", "utls": {"enabled": true, "fingerprint": "chrome"},
+       "reality": {"enabled": true, "public_key": "DUxgIPaAJLsABAx8rW_FYjbVJoBLi5omZXj2ynUXqg8", "short_id": "6ba85179e30d4fc2"}}},
+    {"type": "trojan", "tag": "trojan-grpc", "server": "1.1.1.4", "server_port": 443, "password": "pw",
+     "tls": {"enabled": true, "server_name": "a.com"}, "transport": {"type": "grpc", "service_name": "svc"}},
+    {"type": "hysteria2", "tag": "hy2", "server": "1.1.1.5", "server_port": 443, "password": "pw", "up_mbps": 50, "down_mbps": 100,
+     "obfs": {"type": "salamander", "password": "op"}, "tls": {"enabled": true, "server_name": "a.com", "insecure": true}},
+    {"type": "tuic", "tag": "tuic", "server": "1.1.1.6", "server_port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "password": "pw",
+     "congestion_control": "bbr", "tls": {"enabled": true, "server_name": "a.com", "alpn": ["h3"]}},
+    {"type": "socks", "tag": "socks", "server": "1.1.1.7", "server_port": 1080, "username": "u", "password": "p"},
+    {"type": "http", "tag": "http", "server": "1.1.1.8", "server_port": 8080},
+    {"type": "wireguard", "tag": "wg", "server": "1.1.1.9", "server_port": 51820, "local_address": ["10.0.0.2/32", "fd00::2/128"],
+     "private_key": "eCtXsJZ27+4PbhDkHnB923tkUn2Gj59wZw5wFA75MnU=", "peer_public_key": "Cr8hWlKvtDt7nrvf+f0brNQQzabAqrjfBvas9pmowjo=", "reserved": [1, 2, 3]},
+    {"type": "shadowtls", "tag": "unsupported", "server": "1.1.1.10", "server_port": 443}
+  ]
+}`
+
+const xrayConfig = `{
+  "outbounds": [
+    {"protocol": "freedom", "tag": "direct"},
+    {"protocol": "vless", "tag": "vless-reality", "settings": {"vnext": [{"address": "2.2.2.1", "port": 443,
+       "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "flow": "xtls-rprx-vision", "encryption": "none"}]}]},
+     "streamSettings": {"network": "tcp", "security": "reality",
+       "realitySettings": {"serverName": "www.apple.com", "fingerprint": "chrome", "publicKey": "DUxgIPaAJLsABAx8rW_FYjbVJoBLi5omZXj2ynUXqg8", "shortId": "6ba8"}}},
+    {"protocol": "vmess", "tag": "vmess-http", "settings": {"vnext": [
+       {"address": "2.2.2.2", "port": 80, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "alterId": 0}]},
+       {"address": "2.2.2.3", "port": 80, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "alterId": 0}]}]},
+     "streamSettings": {"network": "tcp", "tcpSettings": {"header": {"type": "http", "request": {"path": ["/x"], "headers": {"Host": ["a.com"]}}}}}},
+    {"protocol": "trojan", "tag": "trojan-ws", "settings": {"servers": [{"address": "2.2.2.4", "port": 443, "password": "pw"}]},
+     "streamSettings": {"network": "ws", "security": "tls", "tlsSettings": {"serverName": "a.com"}, "wsSettings": {"path": "/ws", "host": "a.com"}}},
+    {"protocol": "shadowsocks", "tag": "ss", "settings": {"servers": [{"address": "2.2.2.5", "port": 8388, "method": "aes-128-gcm", "password": "pw"}]}},
+    {"protocol": "socks", "tag": "socks", "settings": {"servers": [{"address": "2.2.2.6", "port": 1080, "users": [{"user": "u", "pass": "p"}]}]}},
+    {"protocol": "wireguard", "tag": "wg", "settings": {"secretKey": "eCtXsJZ27+4PbhDkHnB923tkUn2Gj59wZw5wFA75MnU=", "address": ["10.0.0.2/32"],
+       "peers": [{"endpoint": "2.2.2.7:51820", "publicKey": "Cr8hWlKvtDt7nrvf+f0brNQQzabAqrjfBvas9pmowjo="}]}},
+    {"protocol": "vless", "tag": "xhttp", "settings": {"vnext": [{"address": "2.2.2.8", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]},
+     "streamSettings": {"network": "xhttp"}}
+  ]
+}`
+
+// checkOutbounds converts a config and checks the converted proxies against
+// want, keyed by name, and against the mihomo adapter.
+func checkOutbounds(t *testing.T, data string, wantErrs int, want map[string]map[string]any) {
+	t.Helper()
+	if !IsOutbounds([]byte(data)) {
+		t.Fatal("config is not detected as outbounds")
+	}
+	proxies, errs := ParseOutbounds([]byte(data))
+	if len(errs) != wantErrs {
+		t.Errorf("errors = %v, want %d", errs, wantErrs)
+	}
+	if len(proxies) != len(want) {
+		t.Errorf("got %d proxies, want %d", len(proxies), len(want))
+	}
+	for _, proxy := range proxies {
+		name, _ := proxy["name"].(string)
+		fields, ok := want[name]
+		if !ok {
+			t.Errorf("unexpected proxy %s", name)
+			continue
+		}
+		for key, value := range fields {
+			if got := proxy[key]; !equalValue(got, value) {
+				t.Errorf("%s: %s = %v, want %v", name, key, got, value)
+			}
+		}
+		// wireguard needs the with_gvisor build tag
+		if proxy["type"] == "wireguard" {
+			continue
+		}
+		if _, err := adapter.ParseProxy(proxy); err != nil {
+			t.Errorf("%s: adapter: %v", name, err)
+		}
+	}
+}
+
+func equalValue(got any, want any) bool {
+	if wantMap, ok := want.(map[string]any); ok {
+		gotMap, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range wantMap {
+			if !equalValue(gotMap[key], value) {
+				return false
+			}
+		}
+		return true
+	}
+	return fmt.Sprint(got) == fmt.Sprint(want)
+}
+
+func TestParseSingbox(t *testing.T) {
+	checkOutbounds(t, singboxConfig, 1, map[string]map[string]any{
+		"ss": {"type": "ss", "port": 8388, "plugin": "obfs", "plugin-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+		"vmess-ws": {"type": "vmess", "tls": true, "servername": "a.com", "client-fingerprint": "chrome", "network": "ws",
+			"ws-opts": map[string]any{"path": "/ws", "max-early-data": 2048}},
+		"vless-reality": {"type": "vless", "flow": "xtls-rprx-vision", "reality-opts": map[string]any{"short-id": "6ba85179e30d4fc2"}},
+		"trojan-grpc":   {"type": "trojan", "sni": "a.com", "network": "grpc", "grpc-opts": map[string]any{"grpc-service-name": "svc"}},
+		"hy2":           {"type": "hysteria2", "obfs": "salamander", "up": "50 Mbps", "skip-cert-verify": true},
+		"tuic":          {"type": "tuic", "congestion-controller": "bbr", "alpn": []string{"h3"}},
+		"socks":         {"type": "socks5", "username": "u"},
+		"http":          {"type": "http", "port": 8080},
+		"wg":            {"type": "wireguard", "ip": "10.0.0.2", "ipv6": "fd00::2", "reserved": []int{1, 2, 3}},
+	})
+}
+
+func TestParseXray(t *testing.T) {
+	checkOutbounds(t, xrayConfig, 1, map[string]map[string]any{
+		"vless-reality": {"type": "vless", "servername": "www.apple.com", "client-fingerprint": "chrome", "reality-opts": map[string]any{"short-id": "6ba8"}},
+		"vmess-http 1":  {"server": "2.2.2.2", "network": "http", "http-opts": map[string]any{"path": []string{"/x"}}},
+		"vmess-http 2":  {"server": "2.2.2.3"},
+		"trojan-ws":     {"type": "trojan", "sni": "a.com", "ws-opts": map[string]any{"headers": map[string]string{"Host": "a.com"}}},
+		"ss":            {"type": "ss", "cipher": "aes-128-gcm"},
+		"socks":         {"type": "socks5", "password": "p"},
+		"wg":            {"type": "wireguard", "server": "2.2.2.7", "port": 51820, "ip": "10.0.0.2"},
+	})
+}
+
+func TestIsOutbounds(t *testing.T) {
+	tests := map[string]bool{
+		`[{"type": "shadowsocks", "server": "1.1.1.1", "server_port": 1}]`: true,
+		`{"outbounds": []}`:                        false,
+		`{"version": 1, "servers": []}`:            false,
+		`proxies: []`:                              false,
+		`[{"name": "a"}]`:                          false,
+		`{"outbounds": [{"protocol": "freedom"}]}`: true,
+	}
+	for data, want := range tests {
+		if got := IsOutbounds([]byte(data)); got != want {
+			t.Errorf("IsOutbounds(%s) = %v, want %v", data, got, want)
+		}
+	}
+}
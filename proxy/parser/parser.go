@@ -1,30 +1,109 @@
 package parser
 
 import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 )
 
+// linkParsers maps the scheme of a share link to its parser, the link
+// detection pattern is derived from it.
+var linkParsers = map[string]func(string) (map[string]any, error){
+	"ss":        ParseShadowsocks,
+	"ssr":       ParseSsr,
+	"vmess":     ParseVmess,
+	"vless":     ParseVless,
+	"trojan":    ParseTrojan,
+	"hysteria2": ParseHysteria2,
+	"hy2":       ParseHysteria2,
+	"hysteria":  ParseHysteria,
+	"tuic":      ParseTuic,
+	"wireguard": ParseWireguard,
+	"wg":        ParseWireguard,
+	"socks5":    ParseSocks,
+	"socks":     ParseSocks,
+	"http":      ParseHttp,
+	"https":     ParseHttp,
+}
+
+var linkRegexp = buildLinkRegexp()
+
+func buildLinkRegexp() *regexp.Regexp {
+	schemes := make([]string, 0, len(linkParsers))
+	for scheme := range linkParsers {
+		schemes = append(schemes, regexp.QuoteMeta(scheme))
+	}
+	sort.Strings(schemes)
+	return regexp.MustCompile(`^(` + strings.Join(schemes, "|") + `)://`)
+}
+
+// IsProxyLink reports whether data starts with a supported share link.
+func IsProxyLink(data []byte) bool {
+	return linkRegexp.Match(data)
+}
+
+// ParseProxy parses a share link, it returns nil for unsupported schemes.
 func ParseProxy(proxy string) (map[string]any, error) {
-	if strings.HasPrefix(proxy, "ss://") {
-		return ParseShadowsocks(proxy)
+	proxy = strings.TrimSpace(proxy)
+	scheme, _, ok := strings.Cut(proxy, "://")
+	if !ok {
+		return nil, nil
 	}
-	if strings.HasPrefix(proxy, "trojan://") {
-		return ParseTrojan(proxy)
+	parse, ok := linkParsers[strings.ToLower(scheme)]
+	if !ok {
+		return nil, nil
 	}
-	if strings.HasPrefix(proxy, "vmess://") {
-		return ParseVmess(proxy)
+	return parse(proxy)
+}
+
+// userPassword returns the unescaped user info of a link, keeping the
+// "user:pass" form some clients use for the password.
+func userPassword(user *url.Userinfo) string {
+	if user == nil {
+		return ""
 	}
-	if strings.HasPrefix(proxy, "vless://") {
-		return ParseVless(proxy)
+	if password, ok := user.Password(); ok {
+		return user.Username() + ":" + password
 	}
-	if strings.HasPrefix(proxy, "hysteria2://") {
-		return ParseHysteria2(proxy)
+	return user.Username()
+}
+
+// parseLink parses a share link of the given schemes and its server and port.
+func parseLink(data string, schemes ...string) (*url.URL, int, error) {
+	u, err := url.Parse(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse failed: %v", err)
+	}
+	if !slices.Contains(schemes, strings.ToLower(u.Scheme)) {
+		return nil, 0, fmt.Errorf("not %s format", schemes[0])
 	}
-	if strings.HasPrefix(proxy, "hy2://") {
-		return ParseHysteria2(proxy)
+	if u.Hostname() == "" {
+		return nil, 0, fmt.Errorf("%s server address error", schemes[0])
 	}
-	if strings.HasPrefix(proxy, "ssr://") {
-		return ParseSsr(proxy)
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, 0, fmt.Errorf("format error: incorrect port format")
+	}
+	return u, port, nil
+}
+
+// queryBool accepts the "1" and "true" forms clients use for flags.
+func queryBool(query url.Values, keys ...string) bool {
+	for _, key := range keys {
+		if value := strings.ToLower(query.Get(key)); value == "1" || value == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
 	}
-	return nil, nil
+	return strings.Split(value, ",")
 }
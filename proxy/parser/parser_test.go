@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func vmessLink(config string) string {
+	return "vmess://" + base64.StdEncoding.EncodeToString([]byte(config))
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+	}{
+		{"ss", "ss://YWVzLTEyOC1nY206cHc@1.2.3.4:443#a%20b%2Bc"},
+		{"ss padded", "ss://YWVzLTEyOC1nY206cHc=@1.2.3.4:443#x"},
+		{"ssr", "ssr://" + base64.RawURLEncoding.EncodeToString([]byte("h.com:443:origin:aes-256-cfb:plain:"+
+			base64.RawURLEncoding.EncodeToString([]byte("pw"))+"/?remarks="+base64.RawURLEncoding.EncodeToString([]byte("名字"))+"&obfsparam=&protoparam="))},
+		{"vmess ws", vmessLink(`{"v":"2","ps":"n","add":"h.com","port":"443","id":"11111111-1111-1111-1111-111111111111","aid":"0","net":"ws","host":"hh","path":"/p","tls":"tls","sni":"s","alpn":"h2,http/1.1","fp":"chrome"}`)},
+		{"vmess grpc", vmessLink(`{"v":"2","ps":"n","add":"h.com","port":443,"id":"11111111-1111-1111-1111-111111111111","aid":0,"net":"grpc","path":"svc","tls":""}`)},
+		{"vmess http", vmessLink(`{"v":"2","ps":"n","add":"h.com","port":443,"id":"11111111-1111-1111-1111-111111111111","aid":0,"net":"tcp","type":"http","host":"a.com","path":"/x"}`)},
+		{"vmess h2", vmessLink(`{"v":"2","ps":"n","add":"h.com","port":443,"id":"11111111-1111-1111-1111-111111111111","aid":0,"net":"h2","host":"a.com","path":"/x","tls":"tls"}`)},
+		{"vless reality", "vless://11111111-1111-1111-1111-111111111111@h.com:443?type=tcp&security=reality&pbk=pk&sid=ab&sni=www.apple.com&fp=chrome&flow=xtls-rprx-vision#r"},
+		{"vless ws", "vless://11111111-1111-1111-1111-111111111111@h.com:443?type=ws&security=tls&path=%2Fp&host=hh&sni=s&alpn=h2#w"},
+		{"vless grpc", "vless://11111111-1111-1111-1111-111111111111@h.com:443?type=grpc&security=none&serviceName=svc#g"},
+		{"trojan ws", "trojan://p%40ss@h.com:443?security=tls&sni=x&type=ws&path=%2Fws&host=hh&fp=chrome#t%20t"},
+		{"trojan ipv6", "trojan://pass@[2001:db8::1]:443?security=tls&type=grpc&serviceName=svc#t"},
+		{"trojan colon password", "trojan://pa:ss@h.com:443#colon"},
+		{"hysteria2", "hysteria2://user:pw@h.com:443?sni=s&obfs=salamander&obfs-password=op&insecure=1&mport=1000-2000#h"},
+		{"hy2", "hy2://pw@h.com:443/?sni=s#h"},
+		{"hysteria", "hysteria://h.com:443?protocol=udp&auth=a&peer=s&upmbps=100&downmbps=200&obfsParam=o&alpn=h3#hy"},
+		{"tuic", "tuic://11111111-1111-1111-1111-111111111111:pw@h.com:443?sni=s&congestion_control=bbr&udp_relay_mode=native&alpn=h3#tu"},
+		{"wireguard", "wireguard://cHJpdmF0ZWtleXByaXZhdGVrZXlwcml2YXRla2V5cHI%3D@h.com:51820?publickey=cHVibGlja2V5cHVibGlja2V5cHVibGlja2V5cHVibGk%3D&address=10.0.0.2%2F32,fd00::2%2F128&reserved=1,2,3&mtu=1280#wg"},
+		{"socks5", "socks5://user:pw@h.com:1080#s"},
+		{"http", "http://user:pw@h.com:8080#h"},
+		{"https", "https://h.com:8443?sni=s#hs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := ParseProxy(tt.link)
+			if err != nil || first == nil {
+				t.Fatalf("parse %s: %v", tt.link, err)
+			}
+			link, err := EncodeProxy(first)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			second, err := ParseProxy(link)
+			if err != nil {
+				t.Fatalf("parse encoded %s: %v", link, err)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("unstable round trip through %s\n got  %v\n want %v", link, second, first)
+			}
+			if again, _ := EncodeProxy(second); again != link {
+				t.Errorf("link changed: %s != %s", again, link)
+			}
+		})
+	}
+}
+
+func TestTrojanPasswordWithColon(t *testing.T) {
+	proxy, err := ParseTrojan("trojan://pa:ss@h.com:443#t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxy["password"] != "pa:ss" {
+		t.Errorf("password = %v, want pa:ss", proxy["password"])
+	}
+}
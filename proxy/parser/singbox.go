@@ -0,0 +1,241 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// singboxSkipped are the sing-box outbound types that are not proxies.
+var singboxSkipped = map[string]bool{
+	"direct": true, "block": true, "dns": true, "selector": true, "urltest": true,
+}
+
+// fromSingbox converts a sing-box outbound to a mihomo proxy, nil for
+// outbounds that are not proxies.
+func fromSingbox(outbound map[string]any) (map[string]any, error) {
+	outboundType := cast.ToString(outbound["type"])
+	if singboxSkipped[outboundType] {
+		return nil, nil
+	}
+
+	server := cast.ToString(outbound["server"])
+	port := cast.ToInt(outbound["server_port"])
+	if server == "" || port == 0 {
+		return nil, fmt.Errorf("missing server or server_port")
+	}
+	proxy := map[string]any{
+		"name":   proxyName(outboundTag(outbound), server, port),
+		"server": server,
+		"port":   port,
+	}
+	tls := cast.ToStringMap(outbound["tls"])
+
+	switch outboundType {
+	case "shadowsocks":
+		proxy["type"] = "ss"
+		proxy["cipher"] = cast.ToString(outbound["method"])
+		proxy["password"] = cast.ToString(outbound["password"])
+		if plugin := cast.ToString(outbound["plugin"]); plugin != "" {
+			name, opts, err := mihomoPlugin(plugin, cast.ToString(outbound["plugin_opts"]))
+			if err != nil {
+				return nil, err
+			}
+			proxy["plugin"] = name
+			proxy["plugin-opts"] = opts
+		}
+	case "vmess":
+		proxy["type"] = "vmess"
+		proxy["uuid"] = cast.ToString(outbound["uuid"])
+		proxy["alterId"] = cast.ToInt(outbound["alter_id"])
+		proxy["cipher"] = cast.ToString(outbound["security"])
+		if proxy["cipher"] == "" {
+			proxy["cipher"] = "auto"
+		}
+		setSingboxTLS(proxy, tls, "servername")
+		if err := setSingboxTransport(proxy, outbound, tls); err != nil {
+			return nil, err
+		}
+	case "vless":
+		proxy["type"] = "vless"
+		proxy["uuid"] = cast.ToString(outbound["uuid"])
+		if err := setVlessFlow(proxy, outbound["flow"]); err != nil {
+			return nil, err
+		}
+		setSingboxTLS(proxy, tls, "servername")
+		if err := setSingboxTransport(proxy, outbound, tls); err != nil {
+			return nil, err
+		}
+	case "trojan":
+		proxy["type"] = "trojan"
+		proxy["password"] = cast.ToString(outbound["password"])
+		setSingboxTLS(proxy, tls, "sni")
+		delete(proxy, "tls")
+		if err := setSingboxTransport(proxy, outbound, tls); err != nil {
+			return nil, err
+		}
+	case "hysteria2":
+		proxy["type"] = "hysteria2"
+		proxy["password"] = cast.ToString(outbound["password"])
+		if obfs := cast.ToStringMap(outbound["obfs"]); cast.ToString(obfs["type"]) != "" {
+			proxy["obfs"] = cast.ToString(obfs["type"])
+			proxy["obfs-password"] = cast.ToString(obfs["password"])
+		}
+		setSingboxBandwidth(proxy, outbound)
+		setSingboxTLS(proxy, tls, "sni")
+		delete(proxy, "tls")
+	case "hysteria":
+		proxy["type"] = "hysteria"
+		setNonEmpty(proxy, "auth-str", outbound["auth_str"])
+		setNonEmpty(proxy, "obfs", outbound["obfs"])
+		setSingboxBandwidth(proxy, outbound)
+		setSingboxTLS(proxy, tls, "sni")
+		delete(proxy, "tls")
+	case "tuic":
+		proxy["type"] = "tuic"
+		proxy["uuid"] = cast.ToString(outbound["uuid"])
+		proxy["password"] = cast.ToString(outbound["password"])
+		setNonEmpty(proxy, "congestion-controller", outbound["congestion_control"])
+		setNonEmpty(proxy, "udp-relay-mode", outbound["udp_relay_mode"])
+		setSingboxTLS(proxy, tls, "sni")
+		delete(proxy, "tls")
+	case "socks":
+		if version := cast.ToString(outbound["version"]); version != "" && version != "5" {
+			return nil, fmt.Errorf("unsupported socks version: %s", version)
+		}
+		proxy["type"] = "socks5"
+		setNonEmpty(proxy, "username", outbound["username"])
+		setNonEmpty(proxy, "password", outbound["password"])
+	case "http":
+		proxy["type"] = "http"
+		setNonEmpty(proxy, "username", outbound["username"])
+		setNonEmpty(proxy, "password", outbound["password"])
+		setSingboxTLS(proxy, tls, "sni")
+	case "wireguard":
+		proxy["type"] = "wireguard"
+		proxy["private-key"] = cast.ToString(outbound["private_key"])
+		proxy["public-key"] = cast.ToString(outbound["peer_public_key"])
+		setNonEmpty(proxy, "pre-shared-key", outbound["pre_shared_key"])
+		setWireguardAddress(proxy, cast.ToStringSlice(outbound["local_address"]))
+		if reserved := cast.ToIntSlice(outbound["reserved"]); len(reserved) > 0 {
+			proxy["reserved"] = reserved
+		}
+		if mtu := cast.ToInt(outbound["mtu"]); mtu > 0 {
+			proxy["mtu"] = mtu
+		}
+		proxy["udp"] = true
+	default:
+		return nil, fmt.Errorf("unsupported outbound type: %s", outboundType)
+	}
+	return proxy, nil
+}
+
+// setSingboxTLS sets the tls fields of a proxy, sniKey is "servername" for
+// vmess and vless and "sni" for the other types.
+func setSingboxTLS(proxy map[string]any, tls map[string]any, sniKey string) {
+	if !cast.ToBool(tls["enabled"]) {
+		return
+	}
+	proxy["tls"] = true
+	setNonEmpty(proxy, sniKey, tls["server_name"])
+	if cast.ToBool(tls["insecure"]) {
+		proxy["skip-cert-verify"] = true
+	}
+	if alpn := cast.ToStringSlice(tls["alpn"]); len(alpn) > 0 {
+		proxy["alpn"] = alpn
+	}
+	if utls := cast.ToStringMap(tls["utls"]); cast.ToBool(utls["enabled"]) {
+		setNonEmpty(proxy, "client-fingerprint", utls["fingerprint"])
+	}
+	if reality := cast.ToStringMap(tls["reality"]); cast.ToBool(reality["enabled"]) {
+		proxy["reality-opts"] = map[string]any{
+			"public-key": cast.ToString(reality["public_key"]),
+			"short-id":   cast.ToString(reality["short_id"]),
+		}
+	}
+}
+
+func setSingboxTransport(proxy map[string]any, outbound map[string]any, tls map[string]any) error {
+	transport := cast.ToStringMap(outbound["transport"])
+	switch cast.ToString(transport["type"]) {
+	case "":
+	case "ws":
+		opts := map[string]any{}
+		setNonEmpty(opts, "path", transport["path"])
+		if headers := cast.ToStringMapString(transport["headers"]); len(headers) > 0 {
+			opts["headers"] = headers
+		}
+		if earlyData := cast.ToInt(transport["max_early_data"]); earlyData > 0 {
+			opts["max-early-data"] = earlyData
+			setNonEmpty(opts, "early-data-header-name", transport["early_data_header_name"])
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = opts
+	case "httpupgrade":
+		opts := map[string]any{"v2ray-http-upgrade": true}
+		setNonEmpty(opts, "path", transport["path"])
+		if host := cast.ToString(transport["host"]); host != "" {
+			opts["headers"] = map[string]any{"Host": host}
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = opts
+	case "grpc":
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]any{
+			"grpc-service-name": cast.ToString(transport["service_name"]),
+		}
+	case "http":
+		hosts := cast.ToStringSlice(transport["host"])
+		path := cast.ToString(transport["path"])
+		// the sing-box http transport is h2 over tls and plain http otherwise
+		if cast.ToBool(tls["enabled"]) {
+			opts := map[string]any{}
+			if len(hosts) > 0 {
+				opts["host"] = hosts
+			}
+			if path != "" {
+				opts["path"] = path
+			}
+			proxy["network"] = "h2"
+			proxy["h2-opts"] = opts
+			break
+		}
+		opts := map[string]any{}
+		setNonEmpty(opts, "method", transport["method"])
+		if path != "" {
+			opts["path"] = []string{path}
+		}
+		if len(hosts) > 0 {
+			opts["headers"] = map[string]any{"Host": hosts}
+		}
+		proxy["network"] = "http"
+		proxy["http-opts"] = opts
+	default:
+		return fmt.Errorf("unsupported transport: %v", transport["type"])
+	}
+	return nil
+}
+
+// setSingboxBandwidth converts up_mbps and down_mbps to mihomo "up" and "down".
+func setSingboxBandwidth(proxy map[string]any, outbound map[string]any) {
+	for field, key := range map[string]string{"up_mbps": "up", "down_mbps": "down"} {
+		if mbps := cast.ToInt(outbound[field]); mbps > 0 {
+			proxy[key] = fmt.Sprintf("%d Mbps", mbps)
+		}
+	}
+}
+
+// setWireguardAddress splits the interface addresses into mihomo ip and ipv6.
+func setWireguardAddress(proxy map[string]any, addresses []string) {
+	for _, address := range addresses {
+		ip, _, _ := strings.Cut(address, "/")
+		if strings.Contains(ip, ":") {
+			if _, ok := proxy["ipv6"]; !ok {
+				proxy["ipv6"] = ip
+			}
+		} else if _, ok := proxy["ip"]; !ok {
+			proxy["ip"] = ip
+		}
+	}
+}
@@ -1,58 +1,64 @@
 package parser
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/spf13/cast"
 )
 
+// ParseShadowsocks parses SIP002 links, ss://userinfo@host:port/?plugin=xxx#name
+// with a base64 or plain userinfo, and the legacy fully base64 encoded form.
 func ParseShadowsocks(data string) (map[string]any, error) {
 	if !strings.HasPrefix(data, "ss://") {
 		return nil, fmt.Errorf("not ss format")
 	}
 	data = data[5:]
 
-	if !strings.Contains(data, "@") {
-		if strings.Contains(data, "#") {
-			temp := strings.SplitN(data, "#", 2)
-			data = DecodeBase64(temp[0]) + "#" + temp[1]
-		} else {
-			data = DecodeBase64(data)
-		}
-	}
-	if !strings.Contains(data, "@") && !strings.Contains(data, "#") {
-		return nil, fmt.Errorf("format error: missing @ or # separator")
+	name := ""
+	if idx := strings.Index(data, "#"); idx != -1 {
+		name, _ = url.QueryUnescape(data[idx+1:])
+		data = data[:idx]
 	}
 
-	name := ""
-	if idx := strings.LastIndex(data, "#"); idx != -1 {
-		name = data[idx+1:]
-		name, _ = url.QueryUnescape(name)
+	var rawQuery string
+	if idx := strings.Index(data, "?"); idx != -1 {
+		rawQuery = data[idx+1:]
 		data = data[:idx]
 	}
+	data = strings.TrimSuffix(data, "/")
 
-	parts := strings.SplitN(data, "@", 2)
-	if len(parts) != 2 {
+	if !strings.Contains(data, "@") {
+		// legacy ss://base64(method:password@host:port)
+		data = DecodeBase64(data)
+	}
+	idx := strings.LastIndex(data, "@")
+	if idx == -1 {
 		return nil, fmt.Errorf("format error: missing @ separator")
 	}
+	userInfo, address := data[:idx], data[idx+1:]
 
-	parts[0] = DecodeBase64(parts[0])
-
-	methodAndPassword := strings.SplitN(parts[0], ":", 2)
-	if len(methodAndPassword) != 2 {
+	if unescaped, err := url.PathUnescape(userInfo); err == nil {
+		userInfo = unescaped
+	}
+	if !strings.Contains(userInfo, ":") {
+		userInfo = DecodeBase64(userInfo)
+	}
+	method, password, ok := strings.Cut(userInfo, ":")
+	if !ok {
 		return nil, fmt.Errorf("format error: incorrect encryption method and password format")
 	}
 
-	method := methodAndPassword[0]
-
-	password := DecodeBase64(methodAndPassword[1])
-
-	hostPort := strings.Split(parts[1], ":")
-	if len(hostPort) != 2 {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
 		return nil, fmt.Errorf("format error: incorrect server address format")
 	}
-	port, err := strconv.Atoi(hostPort[1])
+	port, err := strconv.Atoi(portText)
 	if err != nil {
 		return nil, fmt.Errorf("format error: incorrect port format")
 	}
@@ -60,11 +66,215 @@ func ParseShadowsocks(data string) (map[string]any, error) {
 	proxy := map[string]any{
 		"name":     name,
 		"type":     "ss",
-		"server":   hostPort[0],
+		"server":   host,
 		"port":     port,
 		"cipher":   method,
 		"password": password,
 	}
 
+	query, err := sip002Query(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+	if plugin := query.Get("plugin"); plugin != "" {
+		pluginName, pluginOpts, _ := strings.Cut(plugin, ";")
+		name, opts, err := mihomoPlugin(pluginName, pluginOpts)
+		if err != nil {
+			return nil, err
+		}
+		proxy["plugin"] = name
+		proxy["plugin-opts"] = opts
+	}
+	if queryBool(query, "udp") || query.Get("uot") != "" {
+		proxy["udp"] = true
+	}
+
 	return proxy, nil
 }
+
+// sip002Query parses the query of a ss link. Many clients leave the ";" of
+// plugin=obfs-local;obfs=http unescaped, which url.ParseQuery rejects, so
+// the pairs are split by hand.
+func sip002Query(rawQuery string) (url.Values, error) {
+	query := url.Values{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		// "+" is kept, it is not a space in a plugin option
+		value, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("format error: invalid %s parameter: %w", key, err)
+		}
+		query.Add(key, value)
+	}
+	return query, nil
+}
+
+func EncodeShadowsocks(proxy map[string]any) (string, error) {
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte(cast.ToString(proxy["cipher"]) + ":" + cast.ToString(proxy["password"])))
+	link := "ss://" + userInfo + "@" + hostPort(proxy)
+	if plugin := cast.ToString(proxy["plugin"]); plugin != "" {
+		value, err := sip003Plugin(plugin, cast.ToStringMap(proxy["plugin-opts"]))
+		if err != nil {
+			return "", err
+		}
+		link += "/?plugin=" + url.QueryEscape(value)
+	}
+	return link + "#" + escapeName(proxy["name"]), nil
+}
+
+// sip008Config is the SIP008 online config format.
+type sip008Config struct {
+	Version int `json:"version"`
+	Servers []struct {
+		Remarks    string `json:"remarks"`
+		Server     string `json:"server"`
+		ServerPort int    `json:"server_port"`
+		Password   string `json:"password"`
+		Method     string `json:"method"`
+		Plugin     string `json:"plugin"`
+		PluginOpts string `json:"plugin_opts"`
+	} `json:"servers"`
+}
+
+// IsSip008 reports whether data is a SIP008 online config.
+func IsSip008(data []byte) bool {
+	var config sip008Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return false
+	}
+	return config.Version == 1 && len(config.Servers) > 0
+}
+
+// ParseSip008 converts the servers of a SIP008 online config to mihomo
+// proxies, servers with an unsupported plugin are returned as errors.
+func ParseSip008(data []byte) ([]map[string]any, []error) {
+	var config sip008Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, []error{fmt.Errorf("parse sip008 failed: %w", err)}
+	}
+
+	proxies := make([]map[string]any, 0, len(config.Servers))
+	errs := make([]error, 0)
+	for i, server := range config.Servers {
+		if server.Server == "" || server.ServerPort == 0 {
+			errs = append(errs, fmt.Errorf("server %d %q: missing server or server_port", i+1, server.Remarks))
+			continue
+		}
+		proxy := map[string]any{
+			"name":     proxyName(server.Remarks, server.Server, server.ServerPort),
+			"type":     "ss",
+			"server":   server.Server,
+			"port":     server.ServerPort,
+			"cipher":   server.Method,
+			"password": server.Password,
+		}
+		if server.Plugin != "" {
+			name, opts, err := mihomoPlugin(server.Plugin, server.PluginOpts)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("server %d %q: %w", i+1, server.Remarks, err))
+				continue
+			}
+			proxy["plugin"] = name
+			proxy["plugin-opts"] = opts
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, errs
+}
+
+// mihomoPlugin converts a SIP003 plugin and its options to the mihomo form.
+func mihomoPlugin(plugin string, pluginOpts string) (string, map[string]any, error) {
+	values := map[string]string{}
+	flags := map[string]bool{}
+	for _, opt := range strings.Split(pluginOpts, ";") {
+		if key, value, ok := strings.Cut(opt, "="); ok {
+			values[key] = value
+		} else if opt != "" {
+			flags[opt] = true
+		}
+	}
+
+	switch plugin {
+	case "obfs-local", "simple-obfs", "obfs":
+		opts := map[string]any{"mode": values["obfs"]}
+		if opts["mode"] == "" {
+			opts["mode"] = values["mode"]
+		}
+		if host := values["obfs-host"]; host != "" {
+			opts["host"] = host
+		}
+		return "obfs", opts, nil
+	case "v2ray-plugin":
+		mode := values["mode"]
+		if mode == "" {
+			mode = "websocket"
+		}
+		if mode != "websocket" {
+			return "", nil, fmt.Errorf("unsupported v2ray-plugin mode: %s", mode)
+		}
+		opts := map[string]any{"mode": mode}
+		if flags["tls"] || values["tls"] == "true" {
+			opts["tls"] = true
+		}
+		if host := values["host"]; host != "" {
+			opts["host"] = host
+		}
+		if path := values["path"]; path != "" {
+			opts["path"] = path
+		}
+		if mux := values["mux"]; mux != "" && mux != "0" && mux != "false" {
+			opts["mux"] = true
+		}
+		return "v2ray-plugin", opts, nil
+	case "shadow-tls":
+		opts := map[string]any{
+			"host":     values["host"],
+			"password": values["password"],
+		}
+		if version := cast.ToInt(values["version"]); version > 0 {
+			opts["version"] = version
+		}
+		return "shadow-tls", opts, nil
+	}
+	return "", nil, fmt.Errorf("unsupported plugin: %s", plugin)
+}
+
+// sip003Plugin is the reverse of mihomoPlugin.
+func sip003Plugin(plugin string, opts map[string]any) (string, error) {
+	fields := make([]string, 0, 5)
+	add := func(key string, value any) {
+		if s := cast.ToString(value); s != "" {
+			fields = append(fields, key+"="+s)
+		}
+	}
+	switch plugin {
+	case "obfs":
+		fields = append(fields, "obfs-local")
+		add("obfs", opts["mode"])
+		add("obfs-host", opts["host"])
+	case "v2ray-plugin":
+		fields = append(fields, "v2ray-plugin")
+		add("mode", opts["mode"])
+		if cast.ToBool(opts["tls"]) {
+			fields = append(fields, "tls")
+		}
+		add("host", opts["host"])
+		add("path", opts["path"])
+		if cast.ToBool(opts["mux"]) {
+			fields = append(fields, "mux=1")
+		}
+	case "shadow-tls":
+		fields = append(fields, "shadow-tls")
+		add("host", opts["host"])
+		add("password", opts["password"])
+		if version := cast.ToInt(opts["version"]); version > 0 {
+			add("version", version)
+		}
+	default:
+		return "", fmt.Errorf("unsupported plugin: %s", plugin)
+	}
+	return strings.Join(fields, ";"), nil
+}
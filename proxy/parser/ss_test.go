@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/metacubex/mihomo/adapter"
+)
+
+func TestParseShadowsocks(t *testing.T) {
+	userInfo := base64.RawURLEncoding.EncodeToString([]byte("aes-128-gcm:pw"))
+	obfs := map[string]any{"mode": "http", "host": "bing.com"}
+	tests := []struct {
+		name string
+		link string
+		// want lists fields of the parsed proxy, nil when the link is rejected
+		want map[string]any
+	}{
+		{"base64 user info", "ss://" + userInfo + "@1.2.3.4:443#plain",
+			map[string]any{"cipher": "aes-128-gcm", "password": "pw", "server": "1.2.3.4", "port": 443, "name": "plain"}},
+		{"ipv6", "ss://" + userInfo + "@[2001:db8::1]:8388#v6", map[string]any{"server": "2001:db8::1", "port": 8388}},
+		{"legacy", "ss://" + base64.StdEncoding.EncodeToString([]byte("aes-256-gcm:p@ss:w@5.6.7.8:80")) + "#legacy",
+			map[string]any{"cipher": "aes-256-gcm", "password": "p@ss:w", "server": "5.6.7.8"}},
+		{"plain 2022 user info", "ss://2022-blake3-aes-128-gcm:YctPZ6U7xPPcU%2Bgp3u%2BOAw%3D%3D@1.2.3.4:443/#ss2022",
+			map[string]any{"cipher": "2022-blake3-aes-128-gcm", "password": "YctPZ6U7xPPcU+gp3u+OAw=="}},
+		{"obfs escaped", "ss://" + userInfo + "@1.2.3.4:443/?plugin=obfs-local%3Bobfs%3Dhttp%3Bobfs-host%3Dbing.com#obfs",
+			map[string]any{"plugin": "obfs", "plugin-opts": obfs}},
+		{"obfs unescaped", "ss://" + userInfo + "@1.2.3.4:443/?plugin=obfs-local;obfs=http;obfs-host=bing.com#obfs",
+			map[string]any{"plugin": "obfs", "plugin-opts": obfs}},
+		{"simple-obfs with udp", "ss://" + userInfo + "@1.2.3.4:443?udp=1&plugin=simple-obfs;obfs=tls;obfs-host=bing.com#obfs",
+			map[string]any{"plugin": "obfs", "plugin-opts": map[string]any{"mode": "tls", "host": "bing.com"}, "udp": true}},
+		{"v2ray-plugin", "ss://" + userInfo + "@1.2.3.4:443/?plugin=v2ray-plugin%3Bmode%3Dwebsocket%3Btls%3Bhost%3Da.com%3Bpath%3D%2Fws%3Bmux%3D1#v2",
+			map[string]any{"plugin": "v2ray-plugin", "plugin-opts": map[string]any{"mode": "websocket", "tls": true, "host": "a.com", "path": "/ws", "mux": true}}},
+		{"shadow-tls", "ss://" + userInfo + "@1.2.3.4:443?plugin=shadow-tls;host=cloud.tencent.com;password=sp;version=3#stls",
+			map[string]any{"plugin": "shadow-tls", "plugin-opts": map[string]any{"host": "cloud.tencent.com", "password": "sp", "version": 3}}},
+		{"unsupported plugin", "ss://" + userInfo + "@1.2.3.4:443/?plugin=kcptun#bad", nil},
+		{"v2ray-plugin quic", "ss://" + userInfo + "@1.2.3.4:443/?plugin=v2ray-plugin;mode=quic#bad", nil},
+		{"invalid escape", "ss://" + userInfo + "@1.2.3.4:443/?plugin=obfs-local%3#bad", nil},
+		{"ipv6 without brackets", "ss://" + userInfo + "@2001:db8::1:8388#bad", nil},
+		{"missing port", "ss://" + userInfo + "@1.2.3.4#bad", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := ParseShadowsocks(tt.link)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("link accepted: %v", proxy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			for key, value := range tt.want {
+				if got := proxy[key]; !reflect.DeepEqual(got, value) {
+					t.Errorf("%s = %v, want %v", key, got, value)
+				}
+			}
+			if _, err := adapter.ParseProxy(proxy); err != nil {
+				t.Errorf("adapter: %v", err)
+			}
+
+			link, err := EncodeShadowsocks(proxy)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			again, err := ParseShadowsocks(link)
+			if err != nil {
+				t.Fatalf("parse encoded %s: %v", link, err)
+			}
+			// udp is not written to links
+			delete(proxy, "udp")
+			if !reflect.DeepEqual(proxy, again) {
+				t.Errorf("unstable round trip through %s\n got  %v\n want %v", link, again, proxy)
+			}
+		})
+	}
+}
+
+func TestParseSip008(t *testing.T) {
+	data := []byte(`{
+  "version": 1,
+  "servers": [
+    {"id": "1", "remarks": "obfs", "server": "1.1.1.1", "server_port": 8388, "password": "pw", "method": "chacha20-ietf-poly1305",
+     "plugin": "obfs-local", "plugin_opts": "obfs=tls;obfs-host=x.com"},
+    {"server": "::1", "server_port": 8389, "password": "p", "method": "aes-128-gcm"},
+    {"remarks": "kcp", "server": "2.2.2.2", "server_port": 1, "password": "p", "method": "aes-128-gcm", "plugin": "kcptun"},
+    {"remarks": "no port", "server": "3.3.3.3", "password": "p", "method": "aes-128-gcm"}
+  ],
+  "bytes_used": 1024,
+  "bytes_remaining": 2048
+}`)
+	if !IsSip008(data) {
+		t.Fatal("SIP008 config is not detected")
+	}
+	if IsOutbounds(data) {
+		t.Error("SIP008 config is detected as outbounds")
+	}
+
+	proxies, errs := ParseSip008(data)
+	if len(errs) != 2 {
+		t.Errorf("errors = %v, want 2", errs)
+	}
+	want := []map[string]any{
+		{"name": "obfs", "type": "ss", "server": "1.1.1.1", "port": 8388, "cipher": "chacha20-ietf-poly1305", "password": "pw",
+			"plugin": "obfs", "plugin-opts": map[string]any{"mode": "tls", "host": "x.com"}},
+		{"name": "[::1]:8389", "type": "ss", "server": "::1", "port": 8389, "cipher": "aes-128-gcm", "password": "p"},
+	}
+	if !reflect.DeepEqual(proxies, want) {
+		t.Errorf("proxies = %v, want %v", proxies, want)
+	}
+	for _, proxy := range proxies {
+		if _, err := adapter.ParseProxy(proxy); err != nil {
+			t.Errorf("%v: adapter: %v", proxy["name"], err)
+		}
+	}
+}
+
+func TestIsSip008(t *testing.T) {
+	tests := map[string]bool{
+		`{"version": 1, "servers": [{"server": "1.1.1.1", "server_port": 1}]}`: true,
+		`{"version": 2, "servers": [{"server": "1.1.1.1", "server_port": 1}]}`: false,
+		`{"version": 1, "servers": []}`:                                        false,
+		`{"outbounds": [{"type": "direct"}]}`:                                  false,
+		`ss://YWVzLTEyOC1nY206cHc@1.2.3.4:443`:                                 false,
+	}
+	for data, want := range tests {
+		if got := IsSip008([]byte(data)); got != want {
+			t.Errorf("IsSip008(%s) = %v, want %v", data, got, want)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package parser
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/spf13/cast"
 )
 
 func ParseSsr(data string) (map[string]any, error) {
@@ -50,6 +53,7 @@ func ParseSsr(data string) (map[string]any, error) {
 
 	}
 	return map[string]any{
+		"type":           "ssr",
 		"name":           remarks,
 		"server":         server,
 		"port":           port,
@@ -61,3 +65,22 @@ func ParseSsr(data string) (map[string]any, error) {
 		"protocol-param": protoParam,
 	}, nil
 }
+
+func EncodeSsr(proxy map[string]any) (string, error) {
+	encode := func(value any) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(cast.ToString(value)))
+	}
+	serverInfo := strings.Join([]string{
+		cast.ToString(proxy["server"]),
+		strconv.Itoa(cast.ToInt(proxy["port"])),
+		cast.ToString(proxy["protocol"]),
+		cast.ToString(proxy["cipher"]),
+		cast.ToString(proxy["obfs"]),
+		encode(proxy["password"]),
+	}, ":")
+	params := url.Values{}
+	params.Set("obfsparam", encode(proxy["obfs-param"]))
+	params.Set("protoparam", encode(proxy["protocol-param"]))
+	params.Set("remarks", encode(proxy["name"]))
+	return "ssr://" + base64.RawURLEncoding.EncodeToString([]byte(serverInfo+"/?"+params.Encode())), nil
+}
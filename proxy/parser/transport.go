@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// setSecurityOpts maps the tls and reality parameters shared by vless and
+// trojan links, sniKey is the mihomo field of the server name.
+func setSecurityOpts(proxy map[string]any, query url.Values, sniKey string) {
+	security := strings.ToLower(query.Get("security"))
+	switch security {
+	case "tls", "xtls", "reality":
+		proxy["tls"] = true
+	}
+	sni := query.Get("sni")
+	if sni == "" {
+		sni = query.Get("peer")
+	}
+	setNonEmpty(proxy, sniKey, sni)
+	setNonEmpty(proxy, "client-fingerprint", query.Get("fp"))
+	if alpn := splitList(query.Get("alpn")); len(alpn) > 0 {
+		proxy["alpn"] = alpn
+	}
+	if queryBool(query, "allowInsecure", "insecure") {
+		proxy["skip-cert-verify"] = true
+	}
+	if security == "reality" {
+		// spx (spider x) has no mihomo counterpart
+		proxy["reality-opts"] = map[string]any{
+			"public-key": query.Get("pbk"),
+			"short-id":   query.Get("sid"),
+		}
+		// reality is built on uTLS and needs a fingerprint
+		if _, ok := proxy["client-fingerprint"]; !ok {
+			proxy["client-fingerprint"] = "chrome"
+		}
+	}
+}
+
+// setTransportOpts maps the v2ray style transport parameters of vless and
+// trojan links to mihomo network options.
+func setTransportOpts(proxy map[string]any, query url.Values) error {
+	network := strings.ToLower(query.Get("type"))
+	host, path := query.Get("host"), query.Get("path")
+	switch network {
+	case "", "tcp", "raw":
+		if query.Get("headerType") != "http" {
+			return nil
+		}
+		// tcp with http header obfuscation
+		opts := map[string]any{"path": []string{"/"}}
+		if path != "" {
+			opts["path"] = splitList(path)
+		}
+		if host != "" {
+			opts["headers"] = map[string]any{"Host": splitList(host)}
+		}
+		proxy["network"] = "http"
+		proxy["http-opts"] = opts
+	case "ws", "httpupgrade":
+		// the early data size written in the path as ?ed=2048 is read by mihomo
+		opts := map[string]any{}
+		setNonEmpty(opts, "path", path)
+		if host != "" {
+			opts["headers"] = map[string]any{"Host": host}
+		}
+		if network == "httpupgrade" {
+			opts["v2ray-http-upgrade"] = true
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = opts
+	case "grpc":
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]any{
+			"grpc-service-name": query.Get("serviceName"),
+		}
+	case "h2", "http":
+		opts := map[string]any{}
+		if host != "" {
+			opts["host"] = splitList(host)
+		}
+		setNonEmpty(opts, "path", path)
+		proxy["network"] = "h2"
+		proxy["h2-opts"] = opts
+	default:
+		// xhttp, kcp and quic are not supported by mihomo
+		return fmt.Errorf("unsupported network: %s", network)
+	}
+	return nil
+}
+
+// setSecurityQuery is the reverse of setSecurityOpts.
+func setSecurityQuery(query url.Values, proxy map[string]any, sniKey string) {
+	reality := cast.ToStringMap(proxy["reality-opts"])
+	switch {
+	case cast.ToString(reality["public-key"]) != "":
+		query.Set("security", "reality")
+		setQuery(query, "pbk", reality["public-key"])
+		setQuery(query, "sid", reality["short-id"])
+	case cast.ToBool(proxy["tls"]):
+		query.Set("security", "tls")
+	default:
+		query.Set("security", "none")
+	}
+	setQuery(query, "sni", proxy[sniKey])
+	setQuery(query, "fp", proxy["client-fingerprint"])
+	setQuery(query, "alpn", strings.Join(cast.ToStringSlice(proxy["alpn"]), ","))
+	if cast.ToBool(proxy["skip-cert-verify"]) {
+		query.Set("allowInsecure", "1")
+	}
+}
+
+// setTransportQuery is the reverse of setTransportOpts.
+func setTransportQuery(query url.Values, network string, proxy map[string]any) {
+	switch network {
+	case "ws":
+		query.Set("type", "ws")
+		if cast.ToBool(cast.ToStringMap(proxy["ws-opts"])["v2ray-http-upgrade"]) {
+			query.Set("type", "httpupgrade")
+		}
+		host, path := wsHostPath(proxy)
+		setQuery(query, "host", host)
+		setQuery(query, "path", path)
+	case "grpc":
+		query.Set("type", "grpc")
+		setQuery(query, "serviceName", grpcServiceName(proxy))
+	case "h2":
+		// share links call h2 "http"
+		opts := cast.ToStringMap(proxy["h2-opts"])
+		query.Set("type", "http")
+		setQuery(query, "host", strings.Join(cast.ToStringSlice(opts["host"]), ","))
+		setQuery(query, "path", opts["path"])
+	case "http":
+		opts := cast.ToStringMap(proxy["http-opts"])
+		query.Set("type", "tcp")
+		query.Set("headerType", "http")
+		setQuery(query, "host", strings.Join(cast.ToStringSlice(cast.ToStringMap(opts["headers"])["Host"]), ","))
+		setQuery(query, "path", strings.Join(cast.ToStringSlice(opts["path"]), ","))
+	default:
+		query.Set("type", "tcp")
+	}
+}
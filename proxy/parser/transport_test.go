@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/metacubex/mihomo/adapter"
+)
+
+const testRealityKey = "DUxgIPaAJLsABAx8rW_FYjbVJoBLi5omZXj2ynUXqg8"
+
+func TestVlessTrojanTransports(t *testing.T) {
+	vless := "vless://" + testUUID + "@1.1.1.1:443"
+	tests := []struct {
+		name string
+		link string
+		// want lists fields of the parsed proxy, nil when the link is rejected
+		want map[string]any
+	}{
+		{"vless reality", vless + "?encryption=none&security=reality&sni=www.apple.com&fp=chrome&pbk=" + testRealityKey + "&sid=6ba85179e30d4fc2&spx=%2F&type=tcp&flow=xtls-rprx-vision#r",
+			map[string]any{"flow": "xtls-rprx-vision", "servername": "www.apple.com", "tls": true,
+				"reality-opts": map[string]any{"public-key": testRealityKey, "short-id": "6ba85179e30d4fc2"}}},
+		{"vless reality grpc default fingerprint", vless + "?security=reality&sni=a.com&pbk=" + testRealityKey + "&type=grpc&serviceName=g#rg",
+			map[string]any{"client-fingerprint": "chrome", "network": "grpc", "grpc-opts": map[string]any{"grpc-service-name": "g"}}},
+		{"vless httpupgrade", vless + "?security=tls&type=httpupgrade&host=h.com&path=%2Fup#hu",
+			map[string]any{"network": "ws", "ws-opts": map[string]any{"path": "/up", "headers": map[string]any{"Host": "h.com"}, "v2ray-http-upgrade": true}}},
+		{"vless h2", vless + "?security=tls&type=h2&host=a.com,b.com&path=%2Fh#h2",
+			map[string]any{"network": "h2", "h2-opts": map[string]any{"host": []string{"a.com", "b.com"}, "path": "/h"}}},
+		{"vless http is h2", vless + "?security=tls&type=http&host=a.com&path=%2Fh#h2b", map[string]any{"network": "h2"}},
+		{"vless http header", vless[:len(vless)-3] + "80?type=tcp&headerType=http&host=a.com&path=%2Fx#hh",
+			map[string]any{"network": "http", "tls": false, "http-opts": map[string]any{"path": []string{"/x"}, "headers": map[string]any{"Host": []string{"a.com"}}}}},
+		{"vless ws early data", vless + "?security=tls&type=ws&host=a.com&path=%2Fws%3Fed%3D2048&fp=firefox&alpn=h2,http/1.1&allowInsecure=1#ws",
+			map[string]any{"client-fingerprint": "firefox", "skip-cert-verify": true, "alpn": []string{"h2", "http/1.1"},
+				"ws-opts": map[string]any{"path": "/ws?ed=2048", "headers": map[string]any{"Host": "a.com"}}}},
+		{"vless xtls vision udp443", vless + "?security=xtls&flow=xtls-rprx-vision-udp443#x", map[string]any{"tls": true}},
+		{"vless ipv6", "vless://" + testUUID + "@[2001:db8::1]:443?security=none#v6", map[string]any{"server": "2001:db8::1"}},
+		{"vless old xtls flow", vless + "?security=xtls&flow=xtls-rprx-direct#xd", nil},
+		{"vless xhttp", vless + "?security=tls&type=xhttp&path=%2Fx#xh", nil},
+		{"trojan reality grpc", "trojan://pw@1.1.1.1:443?security=reality&sni=a.com&fp=chrome&pbk=" + testRealityKey + "&sid=ab&type=grpc&serviceName=g#tr",
+			map[string]any{"sni": "a.com", "network": "grpc"}},
+		{"trojan ws", "trojan://pw@1.1.1.1:443?security=tls&type=ws&host=a.com&path=%2Fws#tw", map[string]any{"network": "ws"}},
+		{"trojan httpupgrade", "trojan://pw@1.1.1.1:443?type=httpupgrade&path=%2Fu#thu", map[string]any{"network": "ws"}},
+		{"trojan plain", "trojan://pw@1.1.1.1:443#plain", map[string]any{"password": "pw"}},
+		{"trojan h2", "trojan://pw@1.1.1.1:443?type=h2#th2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := ParseProxy(tt.link)
+			if tt.want == nil {
+				if err == nil && proxy != nil {
+					t.Fatalf("link accepted: %v", proxy)
+				}
+				return
+			}
+			if err != nil || proxy == nil {
+				t.Fatalf("parse: %v", err)
+			}
+			for key, value := range tt.want {
+				if got := proxy[key]; !reflect.DeepEqual(got, value) {
+					t.Errorf("%s = %v, want %v", key, got, value)
+				}
+			}
+			if _, err := adapter.ParseProxy(proxy); err != nil {
+				t.Errorf("adapter: %v", err)
+			}
+
+			link, err := EncodeProxy(proxy)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if again, err := ParseProxy(link); err != nil || !reflect.DeepEqual(proxy, again) {
+				t.Errorf("unstable round trip through %s: %v", link, err)
+			}
+		})
+	}
+}
+
+// TestVlessFlowPaths checks that share links and sing-box and Xray outbounds
+// accept and reject the same flows.
+func TestVlessFlowPaths(t *testing.T) {
+	for _, flow := range []string{"xtls-rprx-vision", "xtls-rprx-vision-udp443", "xtls-rprx-direct", "xtls-rprx-origin"} {
+		_, linkErr := ParseVless("vless://" + testUUID + "@1.1.1.1:443?security=tls&flow=" + flow + "#v")
+		_, singboxErr := fromSingbox(map[string]any{
+			"type": "vless", "server": "1.1.1.1", "server_port": 443, "uuid": testUUID, "flow": flow,
+		})
+		_, xrayErr := fromXray(map[string]any{
+			"protocol": "vless",
+			"settings": map[string]any{"vnext": []any{map[string]any{
+				"address": "1.1.1.1", "port": 443, "users": []any{map[string]any{"id": testUUID, "flow": flow}},
+			}}},
+		})
+		results := fmt.Sprint(linkErr == nil, singboxErr == nil, xrayErr == nil)
+		if results != "true true true" && results != "false false false" {
+			t.Errorf("flow %s: link, sing-box and xray accepted = %s", flow, results)
+		}
+	}
+}
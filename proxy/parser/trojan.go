@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/spf13/cast"
 )
 
 func ParseTrojan(data string) (map[string]any, error) {
@@ -17,9 +19,8 @@ func ParseTrojan(data string) (map[string]any, error) {
 		return nil, err
 	}
 
-	password := u.User.String()
-	hostPort := strings.Split(u.Host, ":")
-	if len(hostPort) != 2 {
+	password := userPassword(u.User)
+	if u.Hostname() == "" {
 		return nil, nil
 	}
 
@@ -29,7 +30,7 @@ func ParseTrojan(data string) (map[string]any, error) {
 	}
 
 	params := u.Query()
-	port, err := strconv.Atoi(hostPort[1])
+	port, err := strconv.Atoi(u.Port())
 	if err != nil {
 		return nil, fmt.Errorf("format error: incorrect port format")
 	}
@@ -37,45 +38,30 @@ func ParseTrojan(data string) (map[string]any, error) {
 	proxy := map[string]any{
 		"name":     name,
 		"type":     "trojan",
-		"server":   hostPort[0],
+		"server":   u.Hostname(),
 		"port":     port,
 		"password": password,
-		"network": func() string {
-			if t := params.Get("type"); t != "" {
-				return t
-			} else {
-				return "original"
-			}
-		}(),
-		"skip-cert-verify": params.Get("allowInsecure") == "1",
-		"allowInsecure":    params.Get("allowInsecure"),
 	}
-
-	if params.Get("security") == "tls" {
-		proxy["tls"] = true
-		if sni := params.Get("sni"); sni != "" {
-			proxy["sni"] = sni
-		}
+	setSecurityOpts(proxy, params, "sni")
+	// trojan always runs over tls, mihomo has no tls switch for it
+	delete(proxy, "tls")
+	if err := setTransportOpts(proxy, params); err != nil {
+		return nil, err
 	}
-
-	switch params.Get("type") {
-	case "ws":
-		wsOpts := map[string]any{
-			"path": params.Get("path"),
-		}
-		if host := params.Get("host"); host != "" {
-			wsOpts["headers"] = map[string]any{
-				"Host": host,
-			}
-		}
-		proxy["ws-opts"] = wsOpts
-	case "grpc":
-		if serviceName := params.Get("serviceName"); serviceName != "" {
-			proxy["grpc-opts"] = map[string]any{
-				"serviceName": serviceName,
-			}
-		}
+	// mihomo trojan transports are ws and grpc only
+	if network, _ := proxy["network"].(string); network == "h2" || network == "http" {
+		return nil, fmt.Errorf("unsupported network: %s", network)
 	}
 
 	return proxy, nil
 }
+
+func EncodeTrojan(proxy map[string]any) (string, error) {
+	query := url.Values{}
+	setSecurityQuery(query, proxy, "sni")
+	if query.Get("security") == "none" {
+		query.Set("security", "tls")
+	}
+	setTransportQuery(query, cast.ToString(proxy["network"]), proxy)
+	return buildLink("trojan", url.User(cast.ToString(proxy["password"])), proxy, query), nil
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+func ParseTuic(data string) (map[string]any, error) {
+	u, port, err := parseLink(data, "tuic")
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+
+	proxy := map[string]any{
+		"name":   u.Fragment,
+		"type":   "tuic",
+		"server": u.Hostname(),
+		"port":   port,
+		"udp":    true,
+	}
+	// tuic v5 links carry uuid:password, v4 links only a token
+	if password, ok := u.User.Password(); ok {
+		proxy["uuid"] = u.User.Username()
+		proxy["password"] = password
+	} else {
+		proxy["token"] = u.User.Username()
+	}
+	setNonEmpty(proxy, "sni", query.Get("sni"))
+	setNonEmpty(proxy, "congestion-controller", query.Get("congestion_control"))
+	setNonEmpty(proxy, "udp-relay-mode", query.Get("udp_relay_mode"))
+	if alpn := splitList(query.Get("alpn")); len(alpn) > 0 {
+		proxy["alpn"] = alpn
+	}
+	if queryBool(query, "allow_insecure", "insecure", "allowInsecure") {
+		proxy["skip-cert-verify"] = true
+	}
+	if queryBool(query, "disable_sni") {
+		proxy["disable-sni"] = true
+	}
+	return proxy, nil
+}
+
+func EncodeTuic(proxy map[string]any) (string, error) {
+	user := url.User(cast.ToString(proxy["token"]))
+	if uuid := cast.ToString(proxy["uuid"]); uuid != "" {
+		user = url.UserPassword(uuid, cast.ToString(proxy["password"]))
+	}
+	query := url.Values{}
+	setQuery(query, "sni", proxy["sni"])
+	setQuery(query, "congestion_control", proxy["congestion-controller"])
+	setQuery(query, "udp_relay_mode", proxy["udp-relay-mode"])
+	setQuery(query, "alpn", strings.Join(cast.ToStringSlice(proxy["alpn"]), ","))
+	if cast.ToBool(proxy["skip-cert-verify"]) {
+		query.Set("allow_insecure", "1")
+	}
+	if cast.ToBool(proxy["disable-sni"]) {
+		query.Set("disable_sni", "1")
+	}
+	return buildLink("tuic", user, proxy, query), nil
+}
@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/spf13/cast"
 )
 
 func ParseVless(data string) (map[string]any, error) {
@@ -17,8 +19,7 @@ func ParseVless(data string) (map[string]any, error) {
 		return nil, fmt.Errorf("not vless format")
 	}
 
-	hostPort := strings.Split(parsedURL.Host, ":")
-	if len(hostPort) != 2 {
+	if parsedURL.Hostname() == "" {
 		return nil, nil
 	}
 
@@ -30,40 +31,48 @@ func ParseVless(data string) (map[string]any, error) {
 	query := parsedURL.Query()
 
 	proxy := map[string]any{
-		"name":               parsedURL.Fragment,
-		"type":               "vless",
-		"server":             parsedURL.Hostname(),
-		"port":               port,
-		"uuid":               parsedURL.User.String(),
-		"network":            query.Get("type"),
-		"tls":                query.Get("security") != "none",
-		"udp":                query.Get("udp") == "true",
-		"servername":         query.Get("sni"),
-		"flow":               query.Get("flow"),
-		"client-fingerprint": query.Get("fp"),
-		"ws-opts": map[string]any{
-			"path": query.Get("path"),
-			"headers": map[string]any{
-				"Host": query.Get("host"),
-			},
-		},
-		"reality-opts": map[string]any{
-			"public-key": query.Get("pbk"),
-			"short-id":   query.Get("sid"),
-		},
-		"grpc-opts": map[string]any{
-			"grpc-service-name": query.Get("serviceName"),
-		},
-		"security":    query.Get("security"),
-		"sni":         query.Get("sni"),
-		"fp":          query.Get("fp"),
-		"pbk":         query.Get("pbk"),
-		"sid":         query.Get("sid"),
-		"path":        query.Get("path"),
-		"host":        query.Get("host"),
-		"serviceName": query.Get("serviceName"),
-		"mode":        query.Get("mode"),
+		"name":   parsedURL.Fragment,
+		"type":   "vless",
+		"server": parsedURL.Hostname(),
+		"port":   port,
+		"uuid":   parsedURL.User.Username(),
+		"tls":    false,
+		"udp":    query.Get("udp") == "true",
+	}
+	if err := setVlessFlow(proxy, query.Get("flow")); err != nil {
+		return nil, err
+	}
+	setSecurityOpts(proxy, query, "servername")
+	if err := setTransportOpts(proxy, query); err != nil {
+		return nil, err
 	}
 
 	return proxy, nil
 }
+
+// setVlessFlow sets the flow of a vless proxy, share links and sing-box and
+// Xray outbounds go through it alike. mihomo only implements the vision
+// flow, the older xtls flows need a server side xtls it cannot speak.
+func setVlessFlow(proxy map[string]any, flow any) error {
+	value := cast.ToString(flow)
+	if value == "" {
+		return nil
+	}
+	if !strings.HasPrefix(value, "xtls-rprx-vision") {
+		return fmt.Errorf("unsupported flow: %s", value)
+	}
+	proxy["flow"] = value
+	return nil
+}
+
+func EncodeVless(proxy map[string]any) (string, error) {
+	query := url.Values{}
+	query.Set("encryption", "none")
+	setSecurityQuery(query, proxy, "servername")
+	setQuery(query, "flow", proxy["flow"])
+	if cast.ToBool(proxy["udp"]) {
+		query.Set("udp", "true")
+	}
+	setTransportQuery(query, cast.ToString(proxy["network"]), proxy)
+	return buildLink("vless", url.User(cast.ToString(proxy["uuid"])), proxy, query), nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/spf13/cast"
 )
 
 type vmessJson struct {
@@ -92,14 +94,80 @@ func ParseVmess(data string) (map[string]any, error) {
 		proxy["ws-opts"] = wsOpts
 	case "grpc":
 		grpcOpts := map[string]any{
-			"serviceName": vmessInfo.Path,
+			"grpc-service-name": vmessInfo.Path,
 		}
 		proxy["grpc-opts"] = grpcOpts
+	case "h2":
+		h2Opts := map[string]any{
+			"path": vmessInfo.Path,
+		}
+		if vmessInfo.Host != "" {
+			h2Opts["host"] = strings.Split(vmessInfo.Host, ",")
+		}
+		proxy["h2-opts"] = h2Opts
+	case "tcp":
+		if vmessInfo.Type == "http" {
+			proxy["network"] = "http"
+			httpOpts := map[string]any{
+				"path": strings.Split(vmessInfo.Path, ","),
+			}
+			if vmessInfo.Host != "" {
+				httpOpts["headers"] = map[string]any{
+					"Host": strings.Split(vmessInfo.Host, ","),
+				}
+			}
+			proxy["http-opts"] = httpOpts
+		}
 	}
 
 	if vmessInfo.Alpn != "" {
 		proxy["alpn"] = strings.Split(vmessInfo.Alpn, ",")
 	}
+	if vmessInfo.Fp != "" {
+		proxy["client-fingerprint"] = vmessInfo.Fp
+	}
 
 	return proxy, nil
 }
+
+func EncodeVmess(proxy map[string]any) (string, error) {
+	vmessInfo := vmessJson{
+		V:    "2",
+		Ps:   cast.ToString(proxy["name"]),
+		Add:  cast.ToString(proxy["server"]),
+		Port: cast.ToInt(proxy["port"]),
+		Id:   cast.ToString(proxy["uuid"]),
+		Aid:  cast.ToInt(proxy["alterId"]),
+		Scy:  cast.ToString(proxy["cipher"]),
+		Net:  cast.ToString(proxy["network"]),
+		Sni:  cast.ToString(proxy["servername"]),
+		Alpn: strings.Join(cast.ToStringSlice(proxy["alpn"]), ","),
+		Fp:   cast.ToString(proxy["client-fingerprint"]),
+	}
+	if cast.ToBool(proxy["tls"]) {
+		vmessInfo.Tls = "tls"
+	}
+	switch vmessInfo.Net {
+	case "ws":
+		vmessInfo.Host, vmessInfo.Path = wsHostPath(proxy)
+	case "grpc":
+		vmessInfo.Path = grpcServiceName(proxy)
+	case "h2":
+		opts := cast.ToStringMap(proxy["h2-opts"])
+		if hosts := cast.ToStringSlice(opts["host"]); len(hosts) > 0 {
+			vmessInfo.Host = strings.Join(hosts, ",")
+		}
+		vmessInfo.Path = cast.ToString(opts["path"])
+	case "http":
+		opts := cast.ToStringMap(proxy["http-opts"])
+		vmessInfo.Net = "tcp"
+		vmessInfo.Type = "http"
+		vmessInfo.Host = strings.Join(cast.ToStringSlice(cast.ToStringMap(opts["headers"])["Host"]), ",")
+		vmessInfo.Path = strings.Join(cast.ToStringSlice(opts["path"]), ",")
+	}
+	data, err := json.Marshal(vmessInfo)
+	if err != nil {
+		return "", err
+	}
+	return "vmess://" + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// ParseWireguard parses wireguard:// and wg:// links such as
+// wireguard://privatekey@host:port?publickey=xxx&address=10.0.0.2/32,fd00::2/128&reserved=1,2,3&mtu=1280#name
+func ParseWireguard(data string) (map[string]any, error) {
+	u, port, err := parseLink(data, "wireguard", "wg")
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+
+	proxy := map[string]any{
+		"name":        u.Fragment,
+		"type":        "wireguard",
+		"server":      u.Hostname(),
+		"port":        port,
+		"private-key": u.User.Username(),
+		"public-key":  wireguardKey(query, "publickey", "publicKey", "public-key"),
+		"udp":         true,
+	}
+	if proxy["private-key"] == "" || proxy["public-key"] == "" {
+		return nil, fmt.Errorf("wireguard key missing")
+	}
+	if key := wireguardKey(query, "presharedkey", "preSharedKey", "pre-shared-key"); key != "" {
+		proxy["pre-shared-key"] = key
+	}
+	address := splitList(query.Get("address"))
+	if ip := query.Get("ip"); ip != "" {
+		address = append(address, ip)
+	}
+	if ipv6 := query.Get("ipv6"); ipv6 != "" {
+		address = append(address, ipv6)
+	}
+	setWireguardAddress(proxy, address)
+	if reserved := splitList(query.Get("reserved")); len(reserved) > 0 {
+		proxy["reserved"] = cast.ToIntSlice(reserved)
+	}
+	if mtu := cast.ToInt(query.Get("mtu")); mtu > 0 {
+		proxy["mtu"] = mtu
+	}
+	return proxy, nil
+}
+
+// wireguardKey reads a base64 key, a "+" left unescaped in the link is
+// decoded as a space by the query parser.
+func wireguardKey(query url.Values, keys ...string) string {
+	for _, key := range keys {
+		if value := query.Get(key); value != "" {
+			return strings.ReplaceAll(value, " ", "+")
+		}
+	}
+	return ""
+}
+
+func EncodeWireguard(proxy map[string]any) (string, error) {
+	query := url.Values{}
+	setQuery(query, "publickey", proxy["public-key"])
+	setQuery(query, "presharedkey", proxy["pre-shared-key"])
+	address := make([]string, 0, 2)
+	if ip := cast.ToString(proxy["ip"]); ip != "" {
+		address = append(address, ip+"/32")
+	}
+	if ipv6 := cast.ToString(proxy["ipv6"]); ipv6 != "" {
+		address = append(address, ipv6+"/128")
+	}
+	setQuery(query, "address", strings.Join(address, ","))
+	if reserved := cast.ToStringSlice(proxy["reserved"]); len(reserved) > 0 {
+		query.Set("reserved", strings.Join(reserved, ","))
+	}
+	if mtu := cast.ToInt(proxy["mtu"]); mtu > 0 {
+		setQuery(query, "mtu", mtu)
+	}
+	return buildLink("wireguard", url.User(cast.ToString(proxy["private-key"])), proxy, query), nil
+}
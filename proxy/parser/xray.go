@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/spf13/cast"
+)
+
+// xraySkipped are the Xray protocols that are not proxies.
+var xraySkipped = map[string]bool{
+	"freedom": true, "blackhole": true, "dns": true, "loopback": true,
+}
+
+// fromXray converts an Xray outbound to mihomo proxies, one per server.
+func fromXray(outbound map[string]any) ([]map[string]any, error) {
+	protocol := cast.ToString(outbound["protocol"])
+	if xraySkipped[protocol] {
+		return nil, nil
+	}
+	settings := cast.ToStringMap(outbound["settings"])
+	stream := cast.ToStringMap(outbound["streamSettings"])
+	tag := outboundTag(outbound)
+
+	if protocol == "wireguard" {
+		proxy, err := xrayWireguard(tag, settings)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]any{proxy}, nil
+	}
+
+	serverKey := "servers"
+	if protocol == "vmess" || protocol == "vless" {
+		serverKey = "vnext"
+	}
+	servers := cast.ToSlice(settings[serverKey])
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("missing settings.%s", serverKey)
+	}
+
+	proxies := make([]map[string]any, 0, len(servers))
+	for i, item := range servers {
+		server := cast.ToStringMap(item)
+		address := cast.ToString(server["address"])
+		port := cast.ToInt(server["port"])
+		if address == "" || port == 0 {
+			return nil, fmt.Errorf("missing address or port")
+		}
+		name := proxyName(tag, address, port)
+		if len(servers) > 1 {
+			name = fmt.Sprintf("%s %d", name, i+1)
+		}
+		proxy := map[string]any{
+			"name":   name,
+			"server": address,
+			"port":   port,
+		}
+		user := map[string]any{}
+		if users := cast.ToSlice(server["users"]); len(users) > 0 {
+			user = cast.ToStringMap(users[0])
+		}
+
+		switch protocol {
+		case "vmess":
+			proxy["type"] = "vmess"
+			proxy["uuid"] = cast.ToString(user["id"])
+			proxy["alterId"] = cast.ToInt(user["alterId"])
+			proxy["cipher"] = cast.ToString(user["security"])
+			if proxy["cipher"] == "" {
+				proxy["cipher"] = "auto"
+			}
+			setXrayTLS(proxy, stream, "servername")
+		case "vless":
+			proxy["type"] = "vless"
+			proxy["uuid"] = cast.ToString(user["id"])
+			if err := setVlessFlow(proxy, user["flow"]); err != nil {
+				return nil, err
+			}
+			setXrayTLS(proxy, stream, "servername")
+		case "trojan":
+			proxy["type"] = "trojan"
+			proxy["password"] = cast.ToString(server["password"])
+			setXrayTLS(proxy, stream, "sni")
+			delete(proxy, "tls")
+		case "shadowsocks":
+			proxy["type"] = "ss"
+			proxy["cipher"] = cast.ToString(server["method"])
+			proxy["password"] = cast.ToString(server["password"])
+		case "socks":
+			proxy["type"] = "socks5"
+			setNonEmpty(proxy, "username", user["user"])
+			setNonEmpty(proxy, "password", user["pass"])
+		case "http":
+			proxy["type"] = "http"
+			setNonEmpty(proxy, "username", user["user"])
+			setNonEmpty(proxy, "password", user["pass"])
+			setXrayTLS(proxy, stream, "sni")
+		default:
+			return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+		}
+
+		if protocol == "vmess" || protocol == "vless" || protocol == "trojan" {
+			if err := setXrayTransport(proxy, stream); err != nil {
+				return nil, err
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+func setXrayTLS(proxy map[string]any, stream map[string]any, sniKey string) {
+	switch cast.ToString(stream["security"]) {
+	case "tls":
+		tls := cast.ToStringMap(stream["tlsSettings"])
+		proxy["tls"] = true
+		setNonEmpty(proxy, sniKey, tls["serverName"])
+		if cast.ToBool(tls["allowInsecure"]) {
+			proxy["skip-cert-verify"] = true
+		}
+		if alpn := cast.ToStringSlice(tls["alpn"]); len(alpn) > 0 {
+			proxy["alpn"] = alpn
+		}
+		setNonEmpty(proxy, "client-fingerprint", tls["fingerprint"])
+	case "reality":
+		reality := cast.ToStringMap(stream["realitySettings"])
+		proxy["tls"] = true
+		setNonEmpty(proxy, sniKey, reality["serverName"])
+		setNonEmpty(proxy, "client-fingerprint", reality["fingerprint"])
+		proxy["reality-opts"] = map[string]any{
+			"public-key": cast.ToString(reality["publicKey"]),
+			"short-id":   cast.ToString(reality["shortId"]),
+		}
+	}
+}
+
+func setXrayTransport(proxy map[string]any, stream map[string]any) error {
+	switch network := cast.ToString(stream["network"]); network {
+	case "", "tcp", "raw":
+		settings := cast.ToStringMap(stream["tcpSettings"])
+		if len(settings) == 0 {
+			settings = cast.ToStringMap(stream["rawSettings"])
+		}
+		header := cast.ToStringMap(settings["header"])
+		if cast.ToString(header["type"]) != "http" {
+			return nil
+		}
+		// tcp with http header obfuscation
+		request := cast.ToStringMap(header["request"])
+		opts := map[string]any{}
+		setNonEmpty(opts, "method", request["method"])
+		if paths := cast.ToStringSlice(request["path"]); len(paths) > 0 {
+			opts["path"] = paths
+		}
+		if hosts := cast.ToStringSlice(cast.ToStringMap(request["headers"])["Host"]); len(hosts) > 0 {
+			opts["headers"] = map[string]any{"Host": hosts}
+		}
+		proxy["network"] = "http"
+		proxy["http-opts"] = opts
+	case "ws":
+		settings := cast.ToStringMap(stream["wsSettings"])
+		opts := map[string]any{}
+		setNonEmpty(opts, "path", settings["path"])
+		headers := cast.ToStringMapString(settings["headers"])
+		if host := cast.ToString(settings["host"]); host != "" {
+			if headers == nil {
+				headers = map[string]string{}
+			}
+			headers["Host"] = host
+		}
+		if len(headers) > 0 {
+			opts["headers"] = headers
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = opts
+	case "httpupgrade":
+		settings := cast.ToStringMap(stream["httpupgradeSettings"])
+		opts := map[string]any{"v2ray-http-upgrade": true}
+		setNonEmpty(opts, "path", settings["path"])
+		if host := cast.ToString(settings["host"]); host != "" {
+			opts["headers"] = map[string]any{"Host": host}
+		}
+		proxy["network"] = "ws"
+		proxy["ws-opts"] = opts
+	case "grpc":
+		settings := cast.ToStringMap(stream["grpcSettings"])
+		proxy["network"] = "grpc"
+		proxy["grpc-opts"] = map[string]any{
+			"grpc-service-name": cast.ToString(settings["serviceName"]),
+		}
+	case "h2", "http":
+		settings := cast.ToStringMap(stream["httpSettings"])
+		opts := map[string]any{}
+		if hosts := cast.ToStringSlice(settings["host"]); len(hosts) > 0 {
+			opts["host"] = hosts
+		}
+		setNonEmpty(opts, "path", settings["path"])
+		proxy["network"] = "h2"
+		proxy["h2-opts"] = opts
+	default:
+		return fmt.Errorf("unsupported network: %s", network)
+	}
+	return nil
+}
+
+func xrayWireguard(tag string, settings map[string]any) (map[string]any, error) {
+	peers := cast.ToSlice(settings["peers"])
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("missing settings.peers")
+	}
+	peer := cast.ToStringMap(peers[0])
+	host, portText, err := net.SplitHostPort(cast.ToString(peer["endpoint"]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	port := cast.ToInt(portText)
+	proxy := map[string]any{
+		"name":        proxyName(tag, host, port),
+		"type":        "wireguard",
+		"server":      host,
+		"port":        port,
+		"private-key": cast.ToString(settings["secretKey"]),
+		"public-key":  cast.ToString(peer["publicKey"]),
+		"udp":         true,
+	}
+	setNonEmpty(proxy, "pre-shared-key", peer["preSharedKey"])
+	setWireguardAddress(proxy, cast.ToStringSlice(settings["address"]))
+	if reserved := cast.ToIntSlice(settings["reserved"]); len(reserved) > 0 {
+		proxy["reserved"] = reserved
+	}
+	if mtu := cast.ToInt(settings["mtu"]); mtu > 0 {
+		proxy["mtu"] = mtu
+	}
+	return proxy, nil
+}
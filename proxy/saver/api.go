@@ -0,0 +1,150 @@
+package saver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy"
+	"github.com/bestruirui/bestsub/proxy/info"
+	"github.com/bestruirui/bestsub/utils"
+	"github.com/bestruirui/bestsub/utils/log"
+	"gopkg.in/yaml.v3"
+)
+
+// apiNode is a checked node as returned by /api/nodes, credentials such as
+// passwords and uuids are left out.
+type apiNode struct {
+	Name   string         `json:"name"`
+	Type   any            `json:"type"`
+	Server any            `json:"server"`
+	Port   any            `json:"port"`
+	SubUrl string         `json:"sub-url"`
+	Info   info.ProxyInfo `json:"info"`
+}
+
+var (
+	apiNodes     = make([]apiNode, 0)
+	apiNodesLock sync.RWMutex
+	checkTrigger func()
+)
+
+// SetCheckTrigger sets the function the api calls to start a check run.
+func SetCheckTrigger(trigger func()) {
+	checkTrigger = trigger
+}
+
+func setApiNodes(results *[]info.Proxy) {
+	nodes := make([]apiNode, 0, len(*results))
+	for _, result := range *results {
+		name, _ := result.Raw["name"].(string)
+		nodes = append(nodes, apiNode{
+			Name:   name,
+			Type:   result.Raw["type"],
+			Server: result.Raw["server"],
+			Port:   result.Raw["port"],
+			SubUrl: result.SubUrl,
+			Info:   result.Info,
+		})
+	}
+	apiNodesLock.Lock()
+	apiNodes = nodes
+	apiNodesLock.Unlock()
+}
+
+func registerApi(mux *http.ServeMux) {
+	if config.GlobalConfig.Save.ApiToken == "" {
+		log.Info("api-token is not configured, management api disabled")
+		return
+	}
+
+	mux.HandleFunc("GET /api/nodes", apiAuth(func(w http.ResponseWriter, r *http.Request) {
+		apiNodesLock.RLock()
+		defer apiNodesLock.RUnlock()
+		writeJSON(w, http.StatusOK, apiNodes)
+	}))
+
+	mux.HandleFunc("POST /api/check", apiAuth(func(w http.ResponseWriter, r *http.Request) {
+		if checkTrigger == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "check trigger is not available"})
+			return
+		}
+		if utils.GetTaskStatus().Running {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": "check task is already running"})
+			return
+		}
+		go checkTrigger()
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+	}))
+
+	mux.HandleFunc("GET /api/status", apiAuth(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, utils.GetTaskStatus())
+	}))
+
+	mux.HandleFunc("GET /api/subs", apiAuth(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, proxy.GetSubResults())
+	}))
+
+	mux.HandleFunc("GET /api/config", apiAuth(func(w http.ResponseWriter, r *http.Request) {
+		data, err := yaml.Marshal(config.GlobalConfig)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		var effective map[string]any
+		if err := yaml.Unmarshal(data, &effective); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		maskSecrets(effective)
+		writeJSON(w, http.StatusOK, effective)
+	}))
+
+	log.Info("management api enabled at /api")
+}
+
+func apiAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(config.GlobalConfig.Save.ApiToken)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("write api response failed: %v", err)
+	}
+}
+
+// maskSecrets hides tokens and passwords before the config is returned.
+func maskSecrets(values map[string]any) {
+	for key, value := range values {
+		switch v := value.(type) {
+		case map[string]any:
+			maskSecrets(v)
+		case []any:
+			// sub-urls entries may carry auth headers
+			for _, item := range v {
+				if m, ok := item.(map[string]any); ok {
+					maskSecrets(m)
+				}
+			}
+		case string:
+			lower := strings.ToLower(key)
+			if v != "" && (strings.Contains(lower, "token") || strings.Contains(lower, "password") || strings.Contains(lower, "secret") ||
+				lower == "wework-bot" || lower == "authorization" || lower == "cookie") {
+				values[key] = "******"
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package saver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+)
+
+func newApiServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	saved := config.GlobalConfig.Save.ApiToken
+	t.Cleanup(func() { config.GlobalConfig.Save.ApiToken = saved })
+	config.GlobalConfig.Save.ApiToken = "secret-token"
+
+	mux := http.NewServeMux()
+	registerApi(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func apiGet(t *testing.T, server *httptest.Server, path string, authorization string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("GET", server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestApiAuth(t *testing.T) {
+	server := newApiServer(t)
+	tests := map[string]int{
+		"":                             http.StatusUnauthorized,
+		"secret-token":                 http.StatusUnauthorized,
+		"Basic secret-token":           http.StatusUnauthorized,
+		"Bearer wrong":                 http.StatusUnauthorized,
+		"Bearer secret-token-and-more": http.StatusUnauthorized,
+		"Bearer secret-token":          http.StatusOK,
+		"bearer secret-token":          http.StatusOK,
+	}
+	for authorization, want := range tests {
+		if resp := apiGet(t, server, "/api/status", authorization); resp.StatusCode != want {
+			t.Errorf("Authorization %q: status %d, want %d", authorization, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestApiNodesHideCredentials(t *testing.T) {
+	server := newApiServer(t)
+	setApiNodes(&[]info.Proxy{{
+		Raw: map[string]any{"name": "n1", "type": "vless", "server": "1.1.1.1", "port": 443,
+			"uuid": "b831381d-6324-4d53-ad4f-8cda48b30811", "password": "pw"},
+		SubUrl: "https://sub",
+		Info:   info.ProxyInfo{Alive: true, Delay: 120},
+	}})
+	t.Cleanup(func() { setApiNodes(&[]info.Proxy{}) })
+
+	resp := apiGet(t, server, "/api/nodes", "Bearer secret-token")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nodes []map[string]any
+	if err := json.Unmarshal(body, &nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0]["name"] != "n1" || nodes[0]["server"] != "1.1.1.1" || nodes[0]["port"] != float64(443) {
+		t.Fatalf("nodes = %s", body)
+	}
+	for _, secret := range []string{"b831381d", `"pw"`} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("response contains %s: %s", secret, body)
+		}
+	}
+}
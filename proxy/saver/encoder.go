@@ -0,0 +1,94 @@
+package saver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/parser"
+	"github.com/bestruirui/bestsub/utils/log"
+)
+
+// outputEncoder converts the proxies of a category into another client format.
+// The output is saved as <category>-<suffix>.
+type outputEncoder struct {
+	suffix string
+	encode func(proxies []map[string]any) ([]byte, error)
+}
+
+func chooseEncoders() []outputEncoder {
+	encoders := make([]outputEncoder, 0)
+
+	for _, format := range config.GlobalConfig.Save.Formats {
+		switch format {
+		case "singbox":
+			encoders = append(encoders, outputEncoder{suffix: "singbox.json", encode: encodeSingbox})
+		case "base64":
+			encoders = append(encoders, outputEncoder{suffix: "base64.txt", encode: encodeBase64})
+		case "surge":
+			encoders = append(encoders, outputEncoder{suffix: "surge.conf", encode: encodeSurge})
+		case "clash":
+			// clash yaml is always saved
+		default:
+			log.Error("unknown save format: %s", format)
+		}
+	}
+
+	return encoders
+}
+
+func outputName(category string, suffix string) string {
+	return strings.TrimSuffix(category, ".yaml") + "-" + suffix
+}
+
+func encodeSingbox(proxies []map[string]any) ([]byte, error) {
+	outbounds := make([]map[string]any, 0, len(proxies))
+	for _, proxy := range proxies {
+		outbound, err := toSingbox(proxy)
+		if err != nil {
+			log.Debug("skip proxy %v for sing-box: %v", proxy["name"], err)
+			continue
+		}
+		outbounds = append(outbounds, outbound)
+	}
+	if len(outbounds) == 0 {
+		return nil, fmt.Errorf("no proxies supported by sing-box")
+	}
+	return json.MarshalIndent(map[string]any{
+		"outbounds": outbounds,
+	}, "", "  ")
+}
+
+func encodeBase64(proxies []map[string]any) ([]byte, error) {
+	links := make([]string, 0, len(proxies))
+	for _, proxy := range proxies {
+		link, err := parser.EncodeProxy(proxy)
+		if err != nil {
+			log.Debug("skip proxy %v for share link: %v", proxy["name"], err)
+			continue
+		}
+		links = append(links, link)
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("no proxies supported by share links")
+	}
+	return []byte(base64.StdEncoding.EncodeToString([]byte(strings.Join(links, "\n")))), nil
+}
+
+func encodeSurge(proxies []map[string]any) ([]byte, error) {
+	lines := []string{"[Proxy]"}
+	for _, proxy := range proxies {
+		line, err := toSurge(proxy)
+		if err != nil {
+			log.Debug("skip proxy %v for surge: %v", proxy["name"], err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 1 {
+		return nil, fmt.Errorf("no proxies supported by surge")
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
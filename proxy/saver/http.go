@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy"
 	"github.com/bestruirui/bestsub/utils/log"
 )
 
@@ -43,8 +45,11 @@ func startHTTPServer() {
 
 		key := r.URL.Path[1:]
 		if data, exists := httpData[key]; exists {
-			w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+			w.Header().Set("Content-Type", contentType(key))
 			w.Header().Set("status", "ok")
+			if userinfo := proxy.SubUserInfoHeader(); userinfo != "" {
+				w.Header().Set("Subscription-Userinfo", userinfo)
+			}
 			if _, err := w.Write(data); err != nil {
 				http.Error(w, "Failed to write response", http.StatusInternalServerError)
 			}
@@ -184,6 +189,8 @@ func startHTTPServer() {
 		}
 	})
 
+	registerApi(mux)
+
 	httpServer = &http.Server{
 		Addr:         fmt.Sprintf("0.0.0.0:%d", config.GlobalConfig.Save.Port),
 		Handler:      mux,
@@ -202,6 +209,16 @@ func startHTTPServer() {
 	}
 }
 
+func contentType(filename string) string {
+	switch filepath.Ext(filename) {
+	case ".json":
+		return "application/json; charset=utf-8"
+	case ".txt", ".conf":
+		return "text/plain; charset=utf-8"
+	}
+	return "text/yaml; charset=utf-8"
+}
+
 func SaveToHTTP(yamldata []byte, filename string) error {
 	httpDataLock.Lock()
 	defer httpDataLock.Unlock()
@@ -0,0 +1,126 @@
+package saver
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testPassword = "pass"
+
+var outboundTests = []struct {
+	name    string
+	proxy   map[string]any
+	surge   string
+	singbox string
+}{
+	{
+		name: "ss",
+		proxy: map[string]any{"name": "ss", "type": "ss", "server": "1.1.1.1", "port": 8388,
+			"cipher": "aes-128-gcm", "password": testPassword, "udp": true},
+		surge:   "ss = ss, 1.1.1.1, 8388, encrypt-method=aes-128-gcm, password=pass, udp-relay=true",
+		singbox: `{"method":"aes-128-gcm","password":"pass","server":"1.1.1.1","server_port":8388,"tag":"ss","type":"shadowsocks"}`,
+	},
+	{
+		name: "ss obfs",
+		proxy: map[string]any{"name": "ss", "type": "ss", "server": "1.1.1.1", "port": 8388,
+			"cipher": "aes-128-gcm", "password": testPassword,
+			"plugin": "obfs", "plugin-opts": map[string]any{"mode": "http", "host": "bing.com"}},
+		surge:   "ss = ss, 1.1.1.1, 8388, encrypt-method=aes-128-gcm, password=pass, obfs=http, obfs-host=bing.com",
+		singbox: `{"method":"aes-128-gcm","password":"pass","plugin":"obfs-local","plugin_opts":"obfs=http;obfs-host=bing.com","server":"1.1.1.1","server_port":8388,"tag":"ss","type":"shadowsocks"}`,
+	},
+	{
+		name: "vmess ws tls",
+		proxy: map[string]any{"name": "vmess", "type": "vmess", "server": "v.example.com", "port": 443,
+			"uuid": "uuid", "alterId": 0, "cipher": "auto", "tls": true, "servername": "sni.example.com",
+			"network": "ws", "ws-opts": map[string]any{"path": "/ws", "headers": map[string]any{"Host": "h.example.com"}}},
+		surge:   "vmess = vmess, v.example.com, 443, username=uuid, vmess-aead=true, tls=true, sni=sni.example.com, ws=true, ws-path=/ws, ws-headers=Host:h.example.com",
+		singbox: `{"alter_id":0,"security":"auto","server":"v.example.com","server_port":443,"tag":"vmess","tls":{"enabled":true,"server_name":"sni.example.com"},"transport":{"headers":{"Host":"h.example.com"},"path":"/ws","type":"ws"},"type":"vmess","uuid":"uuid"}`,
+	},
+	{
+		name: "trojan",
+		proxy: map[string]any{"name": "trojan", "type": "trojan", "server": "t.example.com", "port": 443,
+			"password": testPassword, "sni": "sni.example.com", "skip-cert-verify": true},
+		surge:   "trojan = trojan, t.example.com, 443, password=pass, sni=sni.example.com, skip-cert-verify=true",
+		singbox: `{"password":"pass","server":"t.example.com","server_port":443,"tag":"trojan","tls":{"enabled":true,"insecure":true,"server_name":"sni.example.com"},"type":"trojan"}`,
+	},
+	{
+		name: "hysteria2",
+		proxy: map[string]any{"name": "hy2", "type": "hysteria2", "server": "h.example.com", "port": 443,
+			"password": testPassword, "sni": "sni.example.com", "up": "50 Mbps", "down": "100"},
+		surge:   "hy2 = hysteria2, h.example.com, 443, password=pass, sni=sni.example.com",
+		singbox: `{"down_mbps":100,"password":"pass","server":"h.example.com","server_port":443,"tag":"hy2","tls":{"enabled":true,"server_name":"sni.example.com"},"type":"hysteria2","up_mbps":50}`,
+	},
+	{
+		name: "tuic",
+		proxy: map[string]any{"name": "tuic", "type": "tuic", "server": "u.example.com", "port": 443,
+			"uuid": "uuid", "password": testPassword, "alpn": []string{"h3"}},
+		surge:   "tuic = tuic-v5, u.example.com, 443, password=pass, uuid=uuid",
+		singbox: `{"password":"pass","server":"u.example.com","server_port":443,"tag":"tuic","tls":{"alpn":["h3"],"enabled":true},"type":"tuic","uuid":"uuid"}`,
+	},
+	{
+		name: "socks5 tls",
+		proxy: map[string]any{"name": "socks", "type": "socks5", "server": "s.example.com", "port": 1080,
+			"username": "user", "password": testPassword, "tls": true},
+		surge:   "socks = socks5-tls, s.example.com, 1080, user, pass",
+		singbox: `{"password":"pass","server":"s.example.com","server_port":1080,"tag":"socks","type":"socks","username":"user","version":"5"}`,
+	},
+	{
+		name:    "http",
+		proxy:   map[string]any{"name": "http", "type": "http", "server": "p.example.com", "port": 8080},
+		surge:   "http = http, p.example.com, 8080",
+		singbox: `{"server":"p.example.com","server_port":8080,"tag":"http","type":"http"}`,
+	},
+	{
+		name: "vless reality",
+		proxy: map[string]any{"name": "vless", "type": "vless", "server": "l.example.com", "port": 443,
+			"uuid": "uuid", "flow": "xtls-rprx-vision", "tls": true, "servername": "sni.example.com",
+			"client-fingerprint": "chrome", "reality-opts": map[string]any{"public-key": "key", "short-id": "ab"}},
+		singbox: `{"flow":"xtls-rprx-vision","server":"l.example.com","server_port":443,"tag":"vless","tls":{"enabled":true,"reality":{"enabled":true,"public_key":"key","short_id":"ab"},"server_name":"sni.example.com","utls":{"enabled":true,"fingerprint":"chrome"}},"type":"vless","uuid":"uuid"}`,
+	},
+}
+
+func TestToSurge(t *testing.T) {
+	for _, tt := range outboundTests {
+		got, err := toSurge(tt.proxy)
+		if tt.surge == "" {
+			if err == nil {
+				t.Errorf("%s: expected error, got %q", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.surge {
+			t.Errorf("%s:\n got  %s\n want %s", tt.name, got, tt.surge)
+		}
+	}
+}
+
+func TestToSingbox(t *testing.T) {
+	for _, tt := range outboundTests {
+		outbound, err := toSingbox(tt.proxy)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got, err := json.Marshal(outbound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.singbox {
+			t.Errorf("%s:\n got  %s\n want %s", tt.name, got, tt.singbox)
+		}
+	}
+}
+
+func TestUnsupportedOutbound(t *testing.T) {
+	proxy := map[string]any{"name": "wg", "type": "wireguard", "server": "w.example.com", "port": 51820}
+	if _, err := toSurge(proxy); err == nil {
+		t.Error("toSurge: expected error for wireguard")
+	}
+	if _, err := toSingbox(proxy); err == nil {
+		t.Error("toSingbox: expected error for wireguard")
+	}
+}
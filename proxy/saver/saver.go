@@ -2,8 +2,10 @@ package saver
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/filter"
 	"github.com/bestruirui/bestsub/proxy/info"
 	"github.com/bestruirui/bestsub/utils"
 	"github.com/bestruirui/bestsub/utils/log"
@@ -21,12 +23,14 @@ type ConfigSaver struct {
 	results     *[]info.Proxy
 	categories  []ProxyCategory
 	saveMethods []func([]byte, string) error
+	encoders    []outputEncoder
 }
 
 func NewConfigSaver(results *[]info.Proxy) *ConfigSaver {
-	return &ConfigSaver{
+	cs := &ConfigSaver{
 		results:     results,
 		saveMethods: chooseSaveMethods(),
+		encoders:    chooseEncoders(),
 		categories: []ProxyCategory{
 			{
 				Name:       "all.yaml",
@@ -47,28 +51,67 @@ func NewConfigSaver(results *[]info.Proxy) *ConfigSaver {
 				Name:       "openai.yaml",
 				Proxies:    make([]map[string]any, 0),
 				SourceData: make([]info.Proxy, 0),
-				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Chatgpt },
+				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Chatgpt.Unlocked() },
 			},
 			{
 				Name:       "youtube.yaml",
 				Proxies:    make([]map[string]any, 0),
 				SourceData: make([]info.Proxy, 0),
-				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Youtube },
+				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Youtube.Unlocked() },
 			},
 			{
 				Name:       "netflix.yaml",
 				Proxies:    make([]map[string]any, 0),
 				SourceData: make([]info.Proxy, 0),
-				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Netflix },
+				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Netflix.Unlocked() },
 			},
 			{
 				Name:       "disney.yaml",
 				Proxies:    make([]map[string]any, 0),
 				SourceData: make([]info.Proxy, 0),
-				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Disney },
+				Filter:     func(result info.Proxy) bool { return result.Info.Unlock.Disney.Unlocked() },
 			},
 		},
 	}
+	cs.categories = append(cs.categories, customCategories()...)
+	return cs
+}
+
+// customCategories builds the categories declared in save.categories.
+func customCategories() []ProxyCategory {
+	categories := make([]ProxyCategory, 0, len(config.GlobalConfig.Save.Categories))
+	for _, category := range config.GlobalConfig.Save.Categories {
+		expr, err := filter.Compile(category.Filter)
+		if err != nil {
+			log.Error("category %s filter is invalid: %v", category.Name, err)
+			continue
+		}
+		categories = append(categories, ProxyCategory{
+			Name:       category.Name,
+			Proxies:    make([]map[string]any, 0),
+			SourceData: make([]info.Proxy, 0),
+			Filter:     func(result info.Proxy) bool { return expr.Match(&result) },
+		})
+	}
+	return categories
+}
+
+// ValiCategories checks the names and filters of save.categories.
+func ValiCategories() error {
+	names := map[string]bool{"all.yaml": true, "openai.yaml": true, "youtube.yaml": true, "netflix.yaml": true, "disney.yaml": true}
+	for _, category := range config.GlobalConfig.Save.Categories {
+		if category.Name == "" || filepath.Base(category.Name) != category.Name {
+			return fmt.Errorf("invalid category name: %q", category.Name)
+		}
+		if names[category.Name] {
+			return fmt.Errorf("duplicate category name: %s", category.Name)
+		}
+		names[category.Name] = true
+		if _, err := filter.Compile(category.Filter); err != nil {
+			return fmt.Errorf("category %s filter %q is invalid: %w", category.Name, category.Filter, err)
+		}
+	}
+	return nil
 }
 
 func SaveConfig(results *[]info.Proxy) ([]info.Proxy, int) {
@@ -78,6 +121,8 @@ func SaveConfig(results *[]info.Proxy) ([]info.Proxy, int) {
 		}
 	}
 
+	setApiNodes(results)
+
 	saver := NewConfigSaver(results)
 	savedCount := 0
 	savedProxies := make([]info.Proxy, 0)
@@ -112,6 +157,10 @@ func (cs *ConfigSaver) Save() error {
 		}
 	}
 
+	if config.GlobalConfig.Save.Template != "" {
+		cs.saveTemplate()
+	}
+
 	return nil
 }
 
@@ -145,6 +194,20 @@ func (cs *ConfigSaver) saveCategory(category ProxyCategory) error {
 		}
 	}
 
+	for _, encoder := range cs.encoders {
+		name := outputName(category.Name, encoder.suffix)
+		data, err := encoder.encode(category.Proxies)
+		if err != nil {
+			log.Error("encode %s failed: %v", name, err)
+			continue
+		}
+		for _, saveMethod := range cs.saveMethods {
+			if err := saveMethod(data, name); err != nil {
+				log.Error("save %s failed with one method: %v", name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -84,16 +84,24 @@ func executeScript(scriptPath string) error {
 func buildScriptProxyPayload(results *[]info.Proxy) []map[string]any {
 	rawProxies := make([]map[string]any, 0, len(*results))
 	for i := range *results {
-		proxyData := make(map[string]any, len((*results)[i].Raw)+6)
+		proxyData := make(map[string]any, len((*results)[i].Raw)+8)
 		for key, value := range (*results)[i].Raw {
 			proxyData[key] = value
 		}
 		proxyData["country"] = (*results)[i].Info.Country
 		proxyData["speed"] = (*results)[i].Info.Speed
-		proxyData["disney"] = (*results)[i].Info.Unlock.Disney
-		proxyData["youtube"] = (*results)[i].Info.Unlock.Youtube
-		proxyData["netflix"] = (*results)[i].Info.Unlock.Netflix
-		proxyData["chatgpt"] = (*results)[i].Info.Unlock.Chatgpt
+		proxyData["disney"] = (*results)[i].Info.Unlock.Disney.Unlocked()
+		proxyData["youtube"] = (*results)[i].Info.Unlock.Youtube.Unlocked()
+		proxyData["netflix"] = (*results)[i].Info.Unlock.Netflix.Unlocked()
+		proxyData["netflix-level"] = (*results)[i].Info.Unlock.Netflix.Level
+		proxyData["chatgpt"] = (*results)[i].Info.Unlock.Chatgpt.Unlocked()
+		proxyData["unlock"] = (*results)[i].Info.Unlock
+		proxyData["risk"] = (*results)[i].Info.Risk
+		proxyData["ip-type"] = (*results)[i].Info.IPType
+		proxyData["uptime"] = (*results)[i].Info.Uptime
+		proxyData["jitter"] = (*results)[i].Info.Jitter
+		proxyData["checks"] = (*results)[i].Info.Checks
+		proxyData["tags"] = (*results)[i].Info.Tags
 		rawProxies = append(rawProxies, proxyData)
 	}
 	return rawProxies
@@ -0,0 +1,236 @@
+package saver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// toSingbox converts a mihomo proxy map to a sing-box outbound.
+func toSingbox(proxy map[string]any) (map[string]any, error) {
+	outbound := map[string]any{
+		"tag":         cast.ToString(proxy["name"]),
+		"server":      cast.ToString(proxy["server"]),
+		"server_port": cast.ToInt(proxy["port"]),
+	}
+
+	switch cast.ToString(proxy["type"]) {
+	case "ss":
+		outbound["type"] = "shadowsocks"
+		outbound["method"] = cast.ToString(proxy["cipher"])
+		outbound["password"] = cast.ToString(proxy["password"])
+		if plugin := cast.ToString(proxy["plugin"]); plugin != "" {
+			name, opts, err := singboxPlugin(plugin, cast.ToStringMap(proxy["plugin-opts"]))
+			if err != nil {
+				return nil, err
+			}
+			outbound["plugin"] = name
+			outbound["plugin_opts"] = opts
+		}
+	case "vmess":
+		outbound["type"] = "vmess"
+		outbound["uuid"] = cast.ToString(proxy["uuid"])
+		outbound["alter_id"] = cast.ToInt(proxy["alterId"])
+		outbound["security"] = cast.ToString(proxy["cipher"])
+		if cast.ToBool(proxy["tls"]) {
+			outbound["tls"] = singboxTLS(proxy, "servername")
+		}
+		if err := setSingboxTransport(outbound, proxy); err != nil {
+			return nil, err
+		}
+	case "vless":
+		outbound["type"] = "vless"
+		outbound["uuid"] = cast.ToString(proxy["uuid"])
+		if flow := cast.ToString(proxy["flow"]); flow != "" {
+			outbound["flow"] = flow
+		}
+		if cast.ToBool(proxy["tls"]) {
+			outbound["tls"] = singboxTLS(proxy, "servername")
+		}
+		if err := setSingboxTransport(outbound, proxy); err != nil {
+			return nil, err
+		}
+	case "trojan":
+		outbound["type"] = "trojan"
+		outbound["password"] = cast.ToString(proxy["password"])
+		outbound["tls"] = singboxTLS(proxy, "sni")
+		if err := setSingboxTransport(outbound, proxy); err != nil {
+			return nil, err
+		}
+	case "hysteria2":
+		outbound["type"] = "hysteria2"
+		outbound["password"] = cast.ToString(proxy["password"])
+		if obfs := cast.ToString(proxy["obfs"]); obfs != "" {
+			outbound["obfs"] = map[string]any{
+				"type":     obfs,
+				"password": cast.ToString(proxy["obfs-password"]),
+			}
+		}
+		setSingboxBandwidth(outbound, proxy)
+		outbound["tls"] = singboxTLS(proxy, "sni")
+	case "hysteria":
+		outbound["type"] = "hysteria"
+		outbound["auth_str"] = cast.ToString(proxy["auth-str"])
+		if obfs := cast.ToString(proxy["obfs"]); obfs != "" {
+			outbound["obfs"] = obfs
+		}
+		setSingboxBandwidth(outbound, proxy)
+		outbound["tls"] = singboxTLS(proxy, "sni")
+	case "tuic":
+		outbound["type"] = "tuic"
+		outbound["uuid"] = cast.ToString(proxy["uuid"])
+		outbound["password"] = cast.ToString(proxy["password"])
+		if cc := cast.ToString(proxy["congestion-controller"]); cc != "" {
+			outbound["congestion_control"] = cc
+		}
+		if mode := cast.ToString(proxy["udp-relay-mode"]); mode != "" {
+			outbound["udp_relay_mode"] = mode
+		}
+		outbound["tls"] = singboxTLS(proxy, "sni")
+	case "socks5":
+		outbound["type"] = "socks"
+		outbound["version"] = "5"
+		setSingboxAuth(outbound, proxy)
+	case "http":
+		outbound["type"] = "http"
+		setSingboxAuth(outbound, proxy)
+		if cast.ToBool(proxy["tls"]) {
+			outbound["tls"] = singboxTLS(proxy, "sni")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported proxy type: %v", proxy["type"])
+	}
+
+	return outbound, nil
+}
+
+func singboxTLS(proxy map[string]any, sniKey string) map[string]any {
+	tls := map[string]any{
+		"enabled": true,
+	}
+	if sni := cast.ToString(proxy[sniKey]); sni != "" {
+		tls["server_name"] = sni
+	}
+	if cast.ToBool(proxy["skip-cert-verify"]) {
+		tls["insecure"] = true
+	}
+	if alpn := cast.ToStringSlice(proxy["alpn"]); len(alpn) > 0 {
+		tls["alpn"] = alpn
+	}
+	if fingerprint := cast.ToString(proxy["client-fingerprint"]); fingerprint != "" {
+		tls["utls"] = map[string]any{
+			"enabled":     true,
+			"fingerprint": fingerprint,
+		}
+	}
+	if reality := cast.ToStringMap(proxy["reality-opts"]); cast.ToString(reality["public-key"]) != "" {
+		tls["reality"] = map[string]any{
+			"enabled":    true,
+			"public_key": cast.ToString(reality["public-key"]),
+			"short_id":   cast.ToString(reality["short-id"]),
+		}
+	}
+	return tls
+}
+
+func setSingboxTransport(outbound map[string]any, proxy map[string]any) error {
+	switch cast.ToString(proxy["network"]) {
+	case "", "tcp":
+	case "ws":
+		opts := cast.ToStringMap(proxy["ws-opts"])
+		if cast.ToBool(opts["v2ray-http-upgrade"]) {
+			transport := map[string]any{"type": "httpupgrade"}
+			setNonEmpty(transport, "path", opts["path"])
+			if host := cast.ToString(cast.ToStringMap(opts["headers"])["Host"]); host != "" {
+				transport["host"] = host
+			}
+			outbound["transport"] = transport
+			return nil
+		}
+		transport := map[string]any{"type": "ws"}
+		setNonEmpty(transport, "path", opts["path"])
+		if headers := cast.ToStringMapString(opts["headers"]); len(headers) > 0 {
+			transport["headers"] = headers
+		}
+		if earlyData := cast.ToInt(opts["max-early-data"]); earlyData > 0 {
+			transport["max_early_data"] = earlyData
+			setNonEmpty(transport, "early_data_header_name", opts["early-data-header-name"])
+		}
+		outbound["transport"] = transport
+	case "grpc":
+		opts := cast.ToStringMap(proxy["grpc-opts"])
+		outbound["transport"] = map[string]any{
+			"type":         "grpc",
+			"service_name": cast.ToString(opts["grpc-service-name"]),
+		}
+	case "h2":
+		opts := cast.ToStringMap(proxy["h2-opts"])
+		transport := map[string]any{"type": "http"}
+		if hosts := cast.ToStringSlice(opts["host"]); len(hosts) > 0 {
+			transport["host"] = hosts
+		}
+		setNonEmpty(transport, "path", opts["path"])
+		outbound["transport"] = transport
+	case "http":
+		opts := cast.ToStringMap(proxy["http-opts"])
+		transport := map[string]any{"type": "http"}
+		if hosts := cast.ToStringSlice(cast.ToStringMap(opts["headers"])["Host"]); len(hosts) > 0 {
+			transport["host"] = hosts
+		}
+		if paths := cast.ToStringSlice(opts["path"]); len(paths) > 0 {
+			transport["path"] = paths[0]
+		}
+		setNonEmpty(transport, "method", opts["method"])
+		outbound["transport"] = transport
+	default:
+		return fmt.Errorf("unsupported network: %v", proxy["network"])
+	}
+	return nil
+}
+
+func singboxPlugin(plugin string, opts map[string]any) (string, string, error) {
+	pluginOpts := make([]string, 0)
+	switch plugin {
+	case "obfs":
+		pluginOpts = append(pluginOpts, "obfs="+cast.ToString(opts["mode"]))
+		if host := cast.ToString(opts["host"]); host != "" {
+			pluginOpts = append(pluginOpts, "obfs-host="+host)
+		}
+		return "obfs-local", strings.Join(pluginOpts, ";"), nil
+	case "v2ray-plugin":
+		pluginOpts = append(pluginOpts, "mode="+cast.ToString(opts["mode"]))
+		if cast.ToBool(opts["tls"]) {
+			pluginOpts = append(pluginOpts, "tls")
+		}
+		if host := cast.ToString(opts["host"]); host != "" {
+			pluginOpts = append(pluginOpts, "host="+host)
+		}
+		if path := cast.ToString(opts["path"]); path != "" {
+			pluginOpts = append(pluginOpts, "path="+path)
+		}
+		return "v2ray-plugin", strings.Join(pluginOpts, ";"), nil
+	}
+	return "", "", fmt.Errorf("unsupported plugin: %s", plugin)
+}
+
+// setSingboxBandwidth converts mihomo "up"/"down" values such as "100 Mbps" to mbps.
+func setSingboxBandwidth(outbound map[string]any, proxy map[string]any) {
+	for key, field := range map[string]string{"up": "up_mbps", "down": "down_mbps"} {
+		value := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(cast.ToString(proxy[key])), "mbps"))
+		if mbps := cast.ToInt(value); mbps > 0 {
+			outbound[field] = mbps
+		}
+	}
+}
+
+func setSingboxAuth(outbound map[string]any, proxy map[string]any) {
+	setNonEmpty(outbound, "username", proxy["username"])
+	setNonEmpty(outbound, "password", proxy["password"])
+}
+
+func setNonEmpty(values map[string]any, key string, value any) {
+	if s := cast.ToString(value); s != "" {
+		values[key] = s
+	}
+}
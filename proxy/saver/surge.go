@@ -0,0 +1,126 @@
+package saver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+var surgeNameReplacer = strings.NewReplacer(",", " ", "=", " ")
+
+// toSurge converts a mihomo proxy map to a line of the Surge [Proxy] section.
+func toSurge(proxy map[string]any) (string, error) {
+	name := surgeNameReplacer.Replace(cast.ToString(proxy["name"]))
+	server := cast.ToString(proxy["server"])
+	port := cast.ToInt(proxy["port"])
+
+	var fields []string
+	switch cast.ToString(proxy["type"]) {
+	case "ss":
+		fields = []string{"ss", server, fmt.Sprint(port),
+			"encrypt-method=" + cast.ToString(proxy["cipher"]),
+			"password=" + cast.ToString(proxy["password"]),
+		}
+		if cast.ToString(proxy["plugin"]) == "obfs" {
+			opts := cast.ToStringMap(proxy["plugin-opts"])
+			fields = append(fields, "obfs="+cast.ToString(opts["mode"]))
+			if host := cast.ToString(opts["host"]); host != "" {
+				fields = append(fields, "obfs-host="+host)
+			}
+		} else if cast.ToString(proxy["plugin"]) != "" {
+			return "", fmt.Errorf("unsupported plugin: %v", proxy["plugin"])
+		}
+	case "vmess":
+		fields = []string{"vmess", server, fmt.Sprint(port), "username=" + cast.ToString(proxy["uuid"])}
+		if cast.ToInt(proxy["alterId"]) == 0 {
+			fields = append(fields, "vmess-aead=true")
+		}
+		if cast.ToBool(proxy["tls"]) {
+			fields = append(fields, "tls=true")
+			fields = append(fields, surgeTLS(proxy, "servername")...)
+		}
+		transport, err := surgeTransport(proxy)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, transport...)
+	case "trojan":
+		fields = []string{"trojan", server, fmt.Sprint(port), "password=" + cast.ToString(proxy["password"])}
+		fields = append(fields, surgeTLS(proxy, "sni")...)
+		transport, err := surgeTransport(proxy)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, transport...)
+	case "hysteria2":
+		fields = []string{"hysteria2", server, fmt.Sprint(port), "password=" + cast.ToString(proxy["password"])}
+		fields = append(fields, surgeTLS(proxy, "sni")...)
+	case "tuic":
+		fields = []string{"tuic-v5", server, fmt.Sprint(port),
+			"password=" + cast.ToString(proxy["password"]),
+			"uuid=" + cast.ToString(proxy["uuid"]),
+		}
+		fields = append(fields, surgeTLS(proxy, "sni")...)
+	case "socks5":
+		scheme := "socks5"
+		if cast.ToBool(proxy["tls"]) {
+			scheme = "socks5-tls"
+		}
+		fields = append([]string{scheme, server, fmt.Sprint(port)}, surgeAuth(proxy)...)
+	case "http":
+		scheme := "http"
+		if cast.ToBool(proxy["tls"]) {
+			scheme = "https"
+		}
+		fields = append([]string{scheme, server, fmt.Sprint(port)}, surgeAuth(proxy)...)
+	default:
+		return "", fmt.Errorf("unsupported proxy type: %v", proxy["type"])
+	}
+
+	if cast.ToBool(proxy["udp"]) {
+		fields = append(fields, "udp-relay=true")
+	}
+
+	return name + " = " + strings.Join(fields, ", "), nil
+}
+
+// surgeTLS returns the sni and certificate options, trojan, hysteria2 and tuic
+// always use TLS in Surge so only vmess adds "tls=true" itself.
+func surgeTLS(proxy map[string]any, sniKey string) []string {
+	var fields []string
+	if sni := cast.ToString(proxy[sniKey]); sni != "" {
+		fields = append(fields, "sni="+sni)
+	}
+	if cast.ToBool(proxy["skip-cert-verify"]) {
+		fields = append(fields, "skip-cert-verify=true")
+	}
+	return fields
+}
+
+func surgeTransport(proxy map[string]any) ([]string, error) {
+	switch cast.ToString(proxy["network"]) {
+	case "", "tcp":
+		return nil, nil
+	case "ws":
+		opts := cast.ToStringMap(proxy["ws-opts"])
+		fields := []string{"ws=true"}
+		if path := cast.ToString(opts["path"]); path != "" {
+			fields = append(fields, "ws-path="+path)
+		}
+		if host := cast.ToString(cast.ToStringMap(opts["headers"])["Host"]); host != "" {
+			fields = append(fields, "ws-headers=Host:"+host)
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("unsupported network: %v", proxy["network"])
+}
+
+func surgeAuth(proxy map[string]any) []string {
+	username := cast.ToString(proxy["username"])
+	password := cast.ToString(proxy["password"])
+	if username == "" && password == "" {
+		return nil
+	}
+	return []string{username, password}
+}
@@ -0,0 +1,242 @@
+package saver
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/utils/log"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	templateOutputName = "mihomo.yaml"
+	templateTestUrl    = "https://www.gstatic.com/generate_204"
+	templateInterval   = 300
+)
+
+// Placeholders that can be used in the proxies list of a template proxy-group.
+const (
+	placeholderAll        = "$all"
+	placeholderCountries  = "$countries"
+	placeholderCategories = "$categories"
+)
+
+func ValiTemplate() error {
+	if config.GlobalConfig.Save.Template == "" {
+		return nil
+	}
+	if _, err := loadTemplate(); err != nil {
+		return err
+	}
+	switch config.GlobalConfig.Save.TemplateGroupType {
+	case "", "url-test", "fallback", "select", "load-balance":
+	default:
+		return fmt.Errorf("unknown template group type: %s", config.GlobalConfig.Save.TemplateGroupType)
+	}
+	return nil
+}
+
+func loadTemplate() (*yaml.Node, error) {
+	data, err := os.ReadFile(config.GlobalConfig.Save.Template)
+	if err != nil {
+		return nil, fmt.Errorf("read template failed: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse template failed: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("template must be a yaml mapping")
+	}
+	return &doc, nil
+}
+
+// renderTemplate builds a complete mihomo config from the template: the
+// checked proxies are injected, a group is generated per country and per
+// category, and everything else in the template, including rules, is kept.
+func (cs *ConfigSaver) renderTemplate() ([]byte, error) {
+	doc, err := loadTemplate()
+	if err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+
+	var all *ProxyCategory
+	for i := range cs.categories {
+		if cs.categories[i].Name == "all.yaml" {
+			all = &cs.categories[i]
+			break
+		}
+	}
+	if all == nil || len(all.Proxies) == 0 {
+		return nil, fmt.Errorf("no proxies to render")
+	}
+
+	names := make([]string, 0, len(all.Proxies))
+	countries := make(map[string][]string)
+	for _, result := range all.SourceData {
+		name := fmt.Sprint(result.Raw["name"])
+		names = append(names, name)
+		country := result.Info.Country
+		if country == "" {
+			country = "UN"
+		}
+		countries[country] = append(countries[country], name)
+	}
+
+	countryCodes := make([]string, 0, len(countries))
+	for country := range countries {
+		countryCodes = append(countryCodes, country)
+	}
+	sort.Strings(countryCodes)
+
+	var templateGroups []map[string]any
+	if node := mappingValue(root, "proxy-groups"); node != nil {
+		if err := node.Decode(&templateGroups); err != nil {
+			return nil, fmt.Errorf("parse template proxy-groups failed: %w", err)
+		}
+	}
+
+	// mihomo rejects a config where a group shares its name with a proxy or
+	// another group
+	used := make(map[string]bool, len(names)+len(templateGroups))
+	for _, name := range names {
+		used[name] = true
+	}
+	for _, group := range templateGroups {
+		used[fmt.Sprint(group["name"])] = true
+	}
+
+	groups := make([]map[string]any, 0)
+	countryGroups := make([]string, 0, len(countryCodes))
+	for _, country := range countryCodes {
+		groupName := uniqueGroupName(country, used)
+		groups = append(groups, newTemplateGroup(groupName, countries[country]))
+		countryGroups = append(countryGroups, groupName)
+	}
+
+	categoryGroups := make([]string, 0)
+	for _, category := range cs.categories {
+		if category.Name == all.Name || len(category.Proxies) == 0 {
+			continue
+		}
+		groupName := uniqueGroupName(strings.TrimSuffix(category.Name, ".yaml"), used)
+		categoryNames := make([]string, 0, len(category.Proxies))
+		for _, proxy := range category.Proxies {
+			categoryNames = append(categoryNames, fmt.Sprint(proxy["name"]))
+		}
+		groups = append(groups, newTemplateGroup(groupName, categoryNames))
+		categoryGroups = append(categoryGroups, groupName)
+	}
+
+	for _, group := range templateGroups {
+		proxies, ok := group["proxies"].([]any)
+		if !ok {
+			continue
+		}
+		expanded := make([]any, 0, len(proxies))
+		for _, proxy := range proxies {
+			switch proxy {
+			case placeholderAll:
+				for _, name := range names {
+					expanded = append(expanded, name)
+				}
+			case placeholderCountries:
+				for _, name := range countryGroups {
+					expanded = append(expanded, name)
+				}
+			case placeholderCategories:
+				for _, name := range categoryGroups {
+					expanded = append(expanded, name)
+				}
+			default:
+				expanded = append(expanded, proxy)
+			}
+		}
+		// mihomo rejects a group without proxies, the group is kept so rules
+		// that reference it still load
+		if len(expanded) == 0 && group["use"] == nil && group["include-all"] == nil && group["include-all-proxies"] == nil {
+			log.Warn("template group %v has no proxies, falling back to DIRECT", group["name"])
+			expanded = append(expanded, "DIRECT")
+		}
+		group["proxies"] = expanded
+	}
+
+	if err := setMappingValue(root, "proxies", all.Proxies); err != nil {
+		return nil, err
+	}
+	if err := setMappingValue(root, "proxy-groups", append(templateGroups, groups...)); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
+
+// uniqueGroupName suffixes a generated group name that is already used by a
+// proxy or group, and marks the returned name as used.
+func uniqueGroupName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	if unique != name {
+		log.Warn("template group %s is already used by a proxy or group, renamed to %s", name, unique)
+	}
+	used[unique] = true
+	return unique
+}
+
+func newTemplateGroup(name string, proxies []string) map[string]any {
+	groupType := config.GlobalConfig.Save.TemplateGroupType
+	if groupType == "" {
+		groupType = "url-test"
+	}
+	group := map[string]any{
+		"name":    name,
+		"type":    groupType,
+		"proxies": proxies,
+	}
+	if groupType != "select" {
+		group["url"] = templateTestUrl
+		group["interval"] = templateInterval
+	}
+	return group
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value any) error {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Errorf("encode %s failed: %w", key, err)
+	}
+	if existing := mappingValue(mapping, key); existing != nil {
+		*existing = node
+		return nil
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &node)
+	return nil
+}
+
+func (cs *ConfigSaver) saveTemplate() {
+	data, err := cs.renderTemplate()
+	if err != nil {
+		log.Error("render %s failed: %v", templateOutputName, err)
+		return
+	}
+	for _, saveMethod := range cs.saveMethods {
+		if err := saveMethod(data, templateOutputName); err != nil {
+			log.Error("save %s failed with one method: %v", templateOutputName, err)
+		}
+	}
+}
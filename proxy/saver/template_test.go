@@ -0,0 +1,143 @@
+package saver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+	"gopkg.in/yaml.v3"
+)
+
+const testTemplate = `mixed-port: 7890
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: [$countries, $categories, DIRECT]
+  - name: All
+    type: select
+    proxies: [$all]
+  - name: JP
+    type: select
+    proxies: [DIRECT]
+rules:
+  - MATCH,Proxy
+`
+
+func testProxy(name string, country string) info.Proxy {
+	return info.Proxy{
+		Raw:  map[string]any{"name": name, "type": "ss"},
+		Info: info.ProxyInfo{Country: country},
+	}
+}
+
+func testCategories() []ProxyCategory {
+	results := []info.Proxy{testProxy("US", "US"), testProxy("jp-1", "JP"), testProxy("node", "")}
+	proxies := make([]map[string]any, 0, len(results))
+	for _, result := range results {
+		proxies = append(proxies, result.Raw)
+	}
+	return []ProxyCategory{
+		{Name: "all.yaml", Proxies: proxies, SourceData: results},
+		{Name: "fast.yaml", Proxies: proxies[:1], SourceData: results[:1]},
+		{Name: "empty.yaml"},
+	}
+}
+
+func renderTestTemplate(t *testing.T, template string, categories []ProxyCategory) map[string]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "template.yaml")
+	if err := os.WriteFile(path, []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := config.GlobalConfig.Save
+	t.Cleanup(func() { config.GlobalConfig.Save = saved })
+	config.GlobalConfig.Save.Template = path
+	config.GlobalConfig.Save.TemplateGroupType = "select"
+
+	cs := &ConfigSaver{categories: categories}
+
+	data, err := cs.renderTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rendered map[string]any
+	if err := yaml.Unmarshal(data, &rendered); err != nil {
+		t.Fatal(err)
+	}
+	return rendered
+}
+
+func TestRenderTemplatePlaceholders(t *testing.T) {
+	rendered := renderTestTemplate(t, testTemplate, testCategories())
+	if rendered["mixed-port"] != 7890 || len(rendered["rules"].([]any)) != 1 {
+		t.Errorf("template fields not kept: %v", rendered)
+	}
+
+	groups := map[string][]any{}
+	var order []string
+	for _, item := range rendered["proxy-groups"].([]any) {
+		group := item.(map[string]any)
+		name := group["name"].(string)
+		groups[name] = group["proxies"].([]any)
+		order = append(order, name)
+	}
+
+	wantOrder := []string{"Proxy", "All", "JP", "JP-2", "UN", "US-2", "fast"}
+	if !reflect.DeepEqual(order, wantOrder) {
+		t.Errorf("groups = %v, want %v", order, wantOrder)
+	}
+	tests := map[string][]any{
+		"Proxy": {"JP-2", "UN", "US-2", "fast", "DIRECT"},
+		"All":   {"US", "jp-1", "node"},
+		"JP":    {"DIRECT"},
+		"JP-2":  {"jp-1"},
+		"US-2":  {"US"},
+		"fast":  {"US"},
+	}
+	for name, want := range tests {
+		if !reflect.DeepEqual(groups[name], want) {
+			t.Errorf("group %s proxies = %v, want %v", name, groups[name], want)
+		}
+	}
+}
+
+func TestRenderTemplateEmptyGroup(t *testing.T) {
+	template := `proxy-groups:
+  - name: Categories
+    type: select
+    proxies: [$categories]
+  - name: Provider
+    type: select
+    use: [remote]
+rules:
+  - MATCH,Categories
+`
+	rendered := renderTestTemplate(t, template, testCategories()[:1])
+	groups := map[string]map[string]any{}
+	for _, item := range rendered["proxy-groups"].([]any) {
+		group := item.(map[string]any)
+		groups[group["name"].(string)] = group
+	}
+	if got := groups["Categories"]["proxies"]; !reflect.DeepEqual(got, []any{"DIRECT"}) {
+		t.Errorf("empty group proxies = %v, want [DIRECT]", got)
+	}
+	if got, ok := groups["Provider"]["proxies"]; ok {
+		t.Errorf("group with use got proxies %v", got)
+	}
+}
+
+func TestUniqueGroupName(t *testing.T) {
+	used := map[string]bool{"US": true, "US-2": true}
+	if got := uniqueGroupName("US", used); got != "US-3" {
+		t.Errorf("uniqueGroupName = %s, want US-3", got)
+	}
+	if got := uniqueGroupName("JP", used); got != "JP" {
+		t.Errorf("uniqueGroupName = %s, want JP", got)
+	}
+	if !used["US-3"] || !used["JP"] {
+		t.Errorf("returned names not marked as used: %v", used)
+	}
+}
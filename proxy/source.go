@@ -0,0 +1,281 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+	"github.com/bestruirui/bestsub/utils/log"
+)
+
+const defaultRetryAfter = 10
+
+// SourceStat is the health of a subscription, kept across runs.
+type SourceStat struct {
+	Url     string    `json:"url"`
+	Alias   string    `json:"alias,omitempty"`
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+	// Count, Unique and Alive are the nodes of the last run as fetched, left
+	// after deduplication and alive after the check
+	Count      int     `json:"count"`
+	Unique     int     `json:"unique"`
+	Alive      int     `json:"alive"`
+	AliveRatio float32 `json:"alive-ratio"`
+	Runs       int     `json:"runs"`
+	Fetched    int     `json:"fetched"`
+	// Failures counts the consecutive runs the source failed or had no alive
+	// node, the source is muted once it reaches source.mute-after
+	Failures  int  `json:"failures"`
+	Muted     bool `json:"muted"`
+	MutedRuns int  `json:"muted-runs"`
+	// UserInfo is the last subscription-userinfo sent by the source
+	UserInfo *SubUserInfo `json:"userinfo,omitempty"`
+	// fetched is set for the sources fetched in the current run
+	fetched bool
+	// changed is set when a local source file changed since the last run
+	changed bool
+}
+
+var (
+	sourceStats     = make(map[string]*SourceStat)
+	sourceStatsLock sync.Mutex
+	sourceStatsPath string
+	// sourceKeys maps the url with date placeholders replaced to the
+	// configured one, proxies carry the former
+	sourceKeys = make(map[string]string)
+)
+
+// LoadSourceStats reads the stats saved by earlier runs, later runs save to
+// the same path.
+func LoadSourceStats(path string) error {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	sourceStatsPath = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read source stats failed: %w", err)
+	}
+	var stats []*SourceStat
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return fmt.Errorf("parse source stats failed: %w", err)
+	}
+	for _, stat := range stats {
+		sourceStats[stat.Url] = stat
+	}
+	return nil
+}
+
+func getSourceStat(subUrl string) *SourceStat {
+	stat, ok := sourceStats[subUrl]
+	if !ok {
+		stat = &SourceStat{Url: subUrl}
+		sourceStats[subUrl] = stat
+	}
+	return stat
+}
+
+// skipMuted reports whether a muted source sits out this run, every
+// source.retry-after runs it is fetched again to see if it recovered.
+func skipMuted(subUrl string) bool {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	stat := getSourceStat(subUrl)
+	if !stat.Muted {
+		return false
+	}
+	if stat.changed {
+		stat.changed = false
+		stat.MutedRuns = 0
+		return false
+	}
+	retryAfter := config.GlobalConfig.Source.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultRetryAfter
+	}
+	if stat.MutedRuns < retryAfter {
+		stat.MutedRuns++
+		return true
+	}
+	stat.MutedRuns = 0
+	return false
+}
+
+// markSourceChanged lets a muted local source be read again in the next run.
+func markSourceChanged(subUrl string) {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	getSourceStat(subUrl).changed = true
+}
+
+func setSubResult(sub config.SubConfig, processedUrl string, count int, err error) {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	sourceKeys[processedUrl] = sub.Url
+
+	stat := getSourceStat(sub.Url)
+	stat.Alias = sub.Alias
+	stat.Time = time.Now()
+	stat.Success = err == nil
+	stat.Error = ""
+	if err != nil {
+		stat.Error = err.Error()
+	}
+	stat.Count = count
+	stat.Unique = 0
+	stat.Alive = 0
+	stat.AliveRatio = 0
+	stat.Runs++
+	if err == nil {
+		stat.Fetched++
+	}
+	stat.fetched = true
+}
+
+func countBySource(proxies []info.Proxy) map[string]int {
+	counts := make(map[string]int)
+	for i := range proxies {
+		subUrl, ok := sourceKeys[proxies[i].SubUrl]
+		if !ok {
+			subUrl = proxies[i].SubUrl
+		}
+		counts[subUrl]++
+	}
+	return counts
+}
+
+// RecordSourceUnique stores how many nodes of every source are left after
+// deduplication.
+func RecordSourceUnique(proxies []info.Proxy) {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	for subUrl, count := range countBySource(proxies) {
+		if stat, ok := sourceStats[subUrl]; ok {
+			stat.Unique = count
+		}
+	}
+}
+
+// RecordSourceAlive stores the alive nodes of every source, mutes sources
+// that keep failing and saves the stats.
+func RecordSourceAlive(proxies []info.Proxy) {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+
+	counts := countBySource(proxies)
+	for subUrl, stat := range sourceStats {
+		if !stat.fetched {
+			continue
+		}
+		stat.fetched = false
+		stat.Alive = counts[subUrl]
+		if stat.Count > 0 {
+			stat.AliveRatio = float32(stat.Alive) * 100 / float32(stat.Count)
+		}
+		if !stat.Success || stat.Alive == 0 {
+			stat.Failures++
+		} else {
+			stat.Failures = 0
+			if stat.Muted {
+				log.Info("subscription link [%s] recovered, unmuted", subUrl)
+			}
+			stat.Muted = false
+			stat.MutedRuns = 0
+		}
+		if muteAfter := config.GlobalConfig.Source.MuteAfter; muteAfter > 0 && stat.Failures >= muteAfter && !stat.Muted {
+			stat.Muted = true
+			log.Warn("subscription link [%s] failed or had no alive proxies for %d runs, muted", subUrl, stat.Failures)
+		}
+	}
+
+	if err := saveSourceStats(); err != nil {
+		log.Error("save source stats failed: %v", err)
+	}
+}
+
+func saveSourceStats() error {
+	if sourceStatsPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(currentSourceStats(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tempPath := sourceStatsPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, sourceStatsPath)
+}
+
+// currentSourceStats lists the stats of the configured subscriptions in
+// config order, stats of removed subscriptions are dropped.
+func currentSourceStats() []SourceStat {
+	stats := make([]SourceStat, 0, len(config.GlobalConfig.SubUrls))
+	for _, sub := range config.GlobalConfig.SubUrls {
+		stat, ok := sourceStats[sub.Url]
+		if !ok {
+			stat = &SourceStat{Url: sub.Url, Alias: sub.Alias}
+		}
+		stats = append(stats, *stat)
+	}
+	return stats
+}
+
+// GetSubResults returns the stats of every configured subscription.
+func GetSubResults() []SourceStat {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	return currentSourceStats()
+}
+
+// SourceReport summarizes the source quality for the run notification.
+func SourceReport() string {
+	stats := GetSubResults()
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Alive > stats[j].Alive })
+
+	now := time.Now()
+	var sb strings.Builder
+	failed, muted := 0, 0
+	for _, stat := range stats {
+		switch {
+		case stat.Muted:
+			muted++
+		case !stat.Success:
+			failed++
+		}
+	}
+	fmt.Fprintf(&sb, "订阅数量: %v 失败: %v 已静默: %v", len(stats), failed, muted)
+	for _, stat := range stats {
+		name := stat.Url
+		if stat.Alias != "" {
+			name = stat.Alias
+		}
+		switch {
+		case stat.Muted:
+			fmt.Fprintf(&sb, "\n[静默] %s 连续%d次不可用", name, stat.Failures)
+		case !stat.Success:
+			fmt.Fprintf(&sb, "\n[失败] %s %s", name, stat.Error)
+		default:
+			fmt.Fprintf(&sb, "\n%s 获取:%d 去重:%d 存活:%d(%.0f%%)", name, stat.Count, stat.Unique, stat.Alive, stat.AliveRatio)
+		}
+		if stat.UserInfo != nil {
+			fmt.Fprintf(&sb, " %s", stat.UserInfo.Summary())
+			if warning := stat.UserInfo.Warning(now); warning != "" {
+				fmt.Fprintf(&sb, " [注意] %s", warning)
+			}
+		}
+	}
+	return sb.String()
+}
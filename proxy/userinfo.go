@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/utils/log"
+)
+
+const (
+	defaultQuotaWarn  = 90
+	defaultExpireWarn = 3
+)
+
+// SubUserInfo is the traffic and expiry of a subscription, read from its
+// subscription-userinfo header. Expire is a unix time, 0 when it never expires.
+type SubUserInfo struct {
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+	Total    int64 `json:"total"`
+	Expire   int64 `json:"expire"`
+}
+
+// ParseSubUserInfo parses a header like
+// "upload=1024; download=2048; total=10737418240; expire=1735660800".
+func ParseSubUserInfo(value string) (*SubUserInfo, bool) {
+	var userinfo SubUserInfo
+	found := false
+	for _, field := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		// some providers send floats such as 1.2e10
+		number, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "upload":
+			userinfo.Upload = int64(number)
+		case "download":
+			userinfo.Download = int64(number)
+		case "total":
+			userinfo.Total = int64(number)
+		case "expire":
+			userinfo.Expire = int64(number)
+		default:
+			continue
+		}
+		found = true
+	}
+	return &userinfo, found
+}
+
+// Used is the consumed traffic in bytes.
+func (u *SubUserInfo) Used() int64 {
+	return u.Upload + u.Download
+}
+
+// String formats the info as a subscription-userinfo header value.
+func (u *SubUserInfo) String() string {
+	return fmt.Sprintf("upload=%d; download=%d; total=%d; expire=%d", u.Upload, u.Download, u.Total, u.Expire)
+}
+
+// Warning describes why the subscription is about to stop working, empty when
+// it has enough traffic and time left.
+func (u *SubUserInfo) Warning(now time.Time) string {
+	quotaWarn := config.GlobalConfig.Source.QuotaWarn
+	if quotaWarn <= 0 {
+		quotaWarn = defaultQuotaWarn
+	}
+	expireWarn := config.GlobalConfig.Source.ExpireWarn
+	if expireWarn <= 0 {
+		expireWarn = defaultExpireWarn
+	}
+
+	warnings := make([]string, 0, 2)
+	if u.Total > 0 && u.Used()*100 >= u.Total*int64(quotaWarn) {
+		warnings = append(warnings, fmt.Sprintf("流量已用%.0f%%", float64(u.Used())*100/float64(u.Total)))
+	}
+	if u.Expire > 0 {
+		left := time.Unix(u.Expire, 0).Sub(now)
+		switch {
+		case left <= 0:
+			warnings = append(warnings, "已到期")
+		case left <= time.Duration(expireWarn)*24*time.Hour:
+			warnings = append(warnings, fmt.Sprintf("%.1f天后到期", left.Hours()/24))
+		}
+	}
+	return strings.Join(warnings, " ")
+}
+
+// Summary is the traffic and expiry line of the source report.
+func (u *SubUserInfo) Summary() string {
+	text := fmt.Sprintf("流量:%s/%s", formatBytes(u.Used()), formatBytes(u.Total))
+	if u.Total <= 0 {
+		text = fmt.Sprintf("流量:%s/不限", formatBytes(u.Used()))
+	}
+	if u.Expire > 0 {
+		text += " 到期:" + time.Unix(u.Expire, 0).Format(time.DateOnly)
+	}
+	return text
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	for _, suffix := range []string{"KB", "MB", "GB", "TB"} {
+		value /= unit
+		if value < unit || suffix == "TB" {
+			return fmt.Sprintf("%.2f %s", value, suffix)
+		}
+	}
+	return ""
+}
+
+// recordSubUserInfo stores the userinfo of a fetched subscription, the last
+// known value is kept when a later fetch does not send it.
+func recordSubUserInfo(subUrl string, header string) {
+	if header == "" {
+		return
+	}
+	userinfo, ok := ParseSubUserInfo(header)
+	if !ok {
+		log.Debug("subscription link [%s] has an invalid subscription-userinfo: %s", subUrl, header)
+		return
+	}
+	if warning := userinfo.Warning(time.Now()); warning != "" {
+		log.Warn("subscription link [%s] is near its quota or expiry: %s", subUrl, warning)
+	}
+
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	getSourceStat(subUrl).UserInfo = userinfo
+}
+
+// SubUserInfoHeader merges the userinfo of the active subscriptions into one
+// subscription-userinfo value: traffic is summed and the earliest expiry wins.
+func SubUserInfoHeader() string {
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+
+	var merged SubUserInfo
+	found := false
+	for _, stat := range currentSourceStats() {
+		if stat.UserInfo == nil || stat.Muted {
+			continue
+		}
+		found = true
+		merged.Upload += stat.UserInfo.Upload
+		merged.Download += stat.UserInfo.Download
+		merged.Total += stat.UserInfo.Total
+		if stat.UserInfo.Expire > 0 && (merged.Expire == 0 || stat.UserInfo.Expire < merged.Expire) {
+			merged.Expire = stat.UserInfo.Expire
+		}
+	}
+	if !found {
+		return ""
+	}
+	return merged.String()
+}
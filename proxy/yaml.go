@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+	"github.com/bestruirui/bestsub/utils/log"
+	"github.com/dlclark/regexp2"
+	"gopkg.in/yaml.v3"
+)
+
+// maxProviderDepth limits how deep proxy-providers pointing at other configs
+// with providers are followed, a provider already on the path is a cycle and
+// is not followed at all.
+const maxProviderDepth = 3
+
+// ParseYamlProxy reads the proxies of a clash/mihomo config, including the
+// http proxy-providers it refers to.
+func ParseYamlProxy(data []byte, proxies *[]info.Proxy, subUrl string) error {
+	return parseYamlConfig(config.SubConfig{Url: subUrl}, data, subUrl, nil, proxies)
+}
+
+func parseYamlConfig(sub config.SubConfig, data []byte, subUrl string, providers []string, proxies *[]info.Proxy) error {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		// control characters sent by some airports break the whole document
+		err = yaml.Unmarshal(removeAllControlCharacters(data), &doc)
+	}
+	if err != nil || len(doc.Content) == 0 {
+		// salvage the items that still parse one by one
+		log.Debug("subscription link [%s] is not valid yaml, parsing it line by line: %v", subUrl, err)
+		return parseYamlLines(data, proxies, subUrl)
+	}
+
+	root := doc.Content[0]
+	count := len(*proxies)
+	switch root.Kind {
+	case yaml.SequenceNode:
+		// a bare list of proxies
+		appendYamlProxies(root, proxies, subUrl)
+	case yaml.MappingNode:
+		if node := mappingValue(root, "proxies"); node != nil {
+			appendYamlProxies(node, proxies, subUrl)
+		}
+		if node := mappingValue(root, "proxy-providers"); node != nil {
+			parseYamlProviders(sub, node, subUrl, providers, proxies)
+		}
+	default:
+		return fmt.Errorf("unexpected yaml document at line %d", root.Line)
+	}
+	if len(*proxies) == count {
+		return fmt.Errorf("no proxies found")
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// appendYamlProxies decodes every item of a proxies list, a broken item is
+// logged with its line and skipped.
+func appendYamlProxies(node *yaml.Node, proxies *[]info.Proxy, subUrl string) {
+	if node.Kind != yaml.SequenceNode {
+		if node.Tag != "!!null" {
+			log.Warn("subscription link [%s] proxies at line %d is not a list", subUrl, node.Line)
+		}
+		return
+	}
+	for _, item := range node.Content {
+		proxy, err := decodeYamlProxy(item)
+		if err != nil {
+			log.Warn("subscription link [%s] %v", subUrl, err)
+			continue
+		}
+		appendParsedYamlProxy([]map[string]any{proxy}, proxies, subUrl)
+	}
+}
+
+// decodeYamlProxy decodes one item of a proxies list, errors carry the line
+// of the item.
+func decodeYamlProxy(item *yaml.Node) (map[string]any, error) {
+	var proxy map[string]any
+	if err := item.Decode(&proxy); err != nil {
+		return nil, fmt.Errorf("proxy at line %d is invalid: %w", item.Line, err)
+	}
+	if proxyType, _ := proxy["type"].(string); proxyType == "" {
+		return nil, fmt.Errorf("proxy at line %d has no type", item.Line)
+	}
+	return proxy, nil
+}
+
+// yamlProvider is the part of a mihomo proxy provider needed to read its
+// proxies.
+type yamlProvider struct {
+	Type          string              `yaml:"type"`
+	Url           string              `yaml:"url"`
+	Header        map[string][]string `yaml:"header"`
+	Filter        string              `yaml:"filter"`
+	ExcludeFilter string              `yaml:"exclude-filter"`
+	Payload       yaml.Node           `yaml:"payload"`
+}
+
+func parseYamlProviders(sub config.SubConfig, node *yaml.Node, subUrl string, providers []string, proxies *[]info.Proxy) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		var provider yamlProvider
+		if err := node.Content[i+1].Decode(&provider); err != nil {
+			log.Warn("subscription link [%s] proxy provider %s at line %d is invalid: %v", subUrl, name, node.Content[i+1].Line, err)
+			continue
+		}
+
+		providerProxies := make([]info.Proxy, 0)
+		switch {
+		case provider.Payload.Kind == yaml.SequenceNode:
+			appendYamlProxies(&provider.Payload, &providerProxies, subUrl)
+		case provider.Type == "http" && provider.Url != "":
+			if provider.Url == subUrl || slices.Contains(providers, provider.Url) {
+				log.Warn("subscription link [%s] proxy provider %s refers back to %s, skip", subUrl, name, provider.Url)
+				continue
+			}
+			if len(providers) >= maxProviderDepth {
+				log.Warn("subscription link [%s] proxy provider %s is nested deeper than %d, skip", subUrl, name, maxProviderDepth)
+				continue
+			}
+			if err := fetchYamlProvider(sub, provider, subUrl, providers, &providerProxies); err != nil {
+				log.Warn("subscription link [%s] proxy provider %s failed: %v", subUrl, name, err)
+				continue
+			}
+		default:
+			log.Debug("subscription link [%s] proxy provider %s of type %s is not supported, skip", subUrl, name, provider.Type)
+			continue
+		}
+
+		providerProxies = filterProviderProxies(provider, providerProxies)
+		log.Debug("subscription link [%s] proxy provider %s has %d proxies", subUrl, name, len(providerProxies))
+		*proxies = append(*proxies, providerProxies...)
+	}
+}
+
+func fetchYamlProvider(sub config.SubConfig, provider yamlProvider, subUrl string, providers []string, proxies *[]info.Proxy) error {
+	// the headers of the subscription are not sent to the provider, it may
+	// live on another host. The url is left empty as a provider is not a
+	// configured source, so no userinfo or source stats are recorded for it
+	providerSub := config.SubConfig{
+		UserAgent: sub.UserAgent,
+		Retry:     sub.Retry,
+		Direct:    sub.Direct,
+		Headers:   make(map[string]string),
+	}
+	for key, values := range provider.Header {
+		if len(values) > 0 {
+			providerSub.Headers[key] = values[0]
+		}
+	}
+	data, _, err := getDateFromSubs(providerSub, provider.Url, nil)
+	if err != nil {
+		return err
+	}
+	// proxies of the provider are counted for the subscription
+	return parseSubData(providerSub, data, subUrl, append(slices.Clip(providers), provider.Url), proxies)
+}
+
+func filterProviderProxies(provider yamlProvider, proxies []info.Proxy) []info.Proxy {
+	var filter, exclude *regexp2.Regexp
+	var err error
+	if provider.Filter != "" {
+		if filter, err = regexp2.Compile(provider.Filter, regexp2.None); err != nil {
+			log.Warn("proxy provider filter %s is invalid: %v", provider.Filter, err)
+		}
+	}
+	if provider.ExcludeFilter != "" {
+		if exclude, err = regexp2.Compile(provider.ExcludeFilter, regexp2.None); err != nil {
+			log.Warn("proxy provider exclude-filter %s is invalid: %v", provider.ExcludeFilter, err)
+		}
+	}
+	if filter == nil && exclude == nil {
+		return proxies
+	}
+
+	filtered := proxies[:0]
+	for _, proxy := range proxies {
+		name := strings.TrimSpace(fmt.Sprint(proxy.Raw["name"]))
+		if filter != nil {
+			if matched, _ := filter.MatchString(name); !matched {
+				continue
+			}
+		}
+		if exclude != nil {
+			if matched, _ := exclude.MatchString(name); matched {
+				continue
+			}
+		}
+		filtered = append(filtered, proxy)
+	}
+	return filtered
+}
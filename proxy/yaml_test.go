@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bestruirui/bestsub/config"
+	"github.com/bestruirui/bestsub/proxy/info"
+	"gopkg.in/yaml.v3"
+)
+
+const fullConfig = `mixed-port: 7890
+dns:
+  enable: true
+proxies:
+  - {name: ss1, type: ss, server: 1.1.1.1, port: 443, cipher: aes-128-gcm, password: pw}
+  - name: vmess1
+    type: vmess
+    server: 2.2.2.2
+    port: 443
+    uuid: b831381d-6324-4d53-ad4f-8cda48b30811
+    alterId: 0
+    cipher: auto
+  - name: broken
+    port: 443
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: [ss1, vmess1]
+rules:
+  - MATCH,Proxy
+`
+
+func proxyNames(proxies []info.Proxy) []string {
+	names := make([]string, 0, len(proxies))
+	for _, proxy := range proxies {
+		names = append(names, fmt.Sprint(proxy.Raw["name"]))
+	}
+	return names
+}
+
+func TestParseYamlFullConfig(t *testing.T) {
+	var proxies []info.Proxy
+	if err := parseYamlConfig(config.SubConfig{}, []byte(fullConfig), "sub", nil, &proxies); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(proxyNames(proxies), ","); got != "ss1,vmess1" {
+		t.Errorf("proxies = %s, want ss1,vmess1", got)
+	}
+}
+
+func TestDecodeYamlProxyLine(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(fullConfig), &doc); err != nil {
+		t.Fatal(err)
+	}
+	items := mappingValue(doc.Content[0], "proxies").Content
+	tests := []struct {
+		item int
+		want string
+	}{
+		{0, ""},
+		{1, ""},
+		{2, "proxy at line 13 has no type"},
+	}
+	for _, tt := range tests {
+		_, err := decodeYamlProxy(items[tt.item])
+		if got := fmt.Sprint(err); (tt.want == "" && err != nil) || (tt.want != "" && got != tt.want) {
+			t.Errorf("item %d: err = %v, want %q", tt.item, err, tt.want)
+		}
+	}
+
+	var invalid yaml.Node
+	if err := yaml.Unmarshal([]byte("proxies:\n  - name: a\n    type: ss\n  - [1, 2]\n"), &invalid); err != nil {
+		t.Fatal(err)
+	}
+	_, err := decodeYamlProxy(mappingValue(invalid.Content[0], "proxies").Content[1])
+	if err == nil || !strings.HasPrefix(err.Error(), "proxy at line 4 is invalid") {
+		t.Errorf("err = %v, want the line of the invalid item", err)
+	}
+}
+
+// providerServer serves /pN as a config with one proxy named pN and a
+// provider pointing at the path returned by next.
+func providerServer(t *testing.T, next func(n int) string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Subscription-Userinfo", "upload=1; download=2; total=1024")
+		var n int
+		fmt.Sscanf(r.URL.Path, "/p%d", &n)
+		body := fmt.Sprintf("proxies:\n  - {name: p%d, type: ss, server: 1.1.1.%d, port: 443, cipher: aes-128-gcm, password: pw}\n", n, n)
+		if path := next(n); path != "" {
+			body += fmt.Sprintf("proxy-providers:\n  next:\n    type: http\n    url: %s%s\n", server.URL, path)
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestParseYamlProviderDepth(t *testing.T) {
+	server, requests := providerServer(t, func(n int) string { return fmt.Sprintf("/p%d", n+1) })
+	sub := config.SubConfig{Url: server.URL + "/p0", Retry: 1, Direct: true}
+	data := fmt.Sprintf("proxy-providers:\n  first:\n    type: http\n    url: %s/p1\n", server.URL)
+
+	var proxies []info.Proxy
+	if err := parseYamlConfig(sub, []byte(data), sub.Url, nil, &proxies); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(proxyNames(proxies), ","); got != "p1,p2,p3" {
+		t.Errorf("proxies = %s, want p1,p2,p3", got)
+	}
+	if requests.Load() != maxProviderDepth {
+		t.Errorf("requests = %d, want %d", requests.Load(), maxProviderDepth)
+	}
+	for _, proxy := range proxies {
+		if proxy.SubUrl != sub.Url {
+			t.Errorf("provider proxy %v counted for %s", proxy.Raw["name"], proxy.SubUrl)
+		}
+	}
+	sourceStatsLock.Lock()
+	defer sourceStatsLock.Unlock()
+	for url := range sourceStats {
+		if strings.HasPrefix(url, server.URL) {
+			t.Errorf("provider recorded as source %s", url)
+		}
+	}
+}
+
+func TestParseYamlProviderCycle(t *testing.T) {
+	// p1 -> p2 -> p1
+	server, requests := providerServer(t, func(n int) string {
+		if n == 1 {
+			return "/p2"
+		}
+		return "/p1"
+	})
+	sub := config.SubConfig{Url: server.URL + "/p1", Retry: 1, Direct: true}
+
+	var proxies []info.Proxy
+	data, _, err := getDateFromSubs(sub, sub.Url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parseSubData(sub, data, sub.Url, nil, &proxies); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(proxyNames(proxies), ","); got != "p1,p2" {
+		t.Errorf("proxies = %s, want p1,p2", got)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("requests = %d, want 2", requests.Load())
+	}
+}
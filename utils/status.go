@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StageIdle  = "idle"
+	StageFetch = "fetch"
+	StageCheck = "check"
+	StageSpeed = "speed"
+	StageSave  = "save"
+)
+
+type TaskStatus struct {
+	Running   bool      `json:"running"`
+	Stage     string    `json:"stage"`
+	Total     int64     `json:"total"`
+	Done      int64     `json:"done"`
+	StartTime time.Time `json:"start-time"`
+	EndTime   time.Time `json:"end-time"`
+	NextCheck time.Time `json:"next-check"`
+}
+
+var (
+	taskStatus     = TaskStatus{Stage: StageIdle}
+	taskStatusLock sync.RWMutex
+	taskDone       atomic.Int64
+)
+
+// TaskStart marks a check run as started, it returns false if one is already running.
+func TaskStart(nextCheck time.Time) bool {
+	taskStatusLock.Lock()
+	defer taskStatusLock.Unlock()
+	if taskStatus.Running {
+		return false
+	}
+	taskStatus = TaskStatus{
+		Running:   true,
+		Stage:     StageFetch,
+		StartTime: time.Now(),
+		EndTime:   taskStatus.EndTime,
+		NextCheck: nextCheck,
+	}
+	taskDone.Store(0)
+	return true
+}
+
+func TaskStage(stage string, total int) {
+	taskStatusLock.Lock()
+	defer taskStatusLock.Unlock()
+	taskStatus.Stage = stage
+	taskStatus.Total = int64(total)
+	taskDone.Store(0)
+}
+
+func TaskProgress() {
+	taskDone.Add(1)
+}
+
+func TaskEnd() {
+	taskStatusLock.Lock()
+	defer taskStatusLock.Unlock()
+	taskStatus.Running = false
+	taskStatus.Stage = StageIdle
+	taskStatus.EndTime = time.Now()
+}
+
+func GetTaskStatus() TaskStatus {
+	taskStatusLock.RLock()
+	defer taskStatusLock.RUnlock()
+	status := taskStatus
+	status.Done = taskDone.Load()
+	return status
+}